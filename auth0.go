@@ -1,7 +1,9 @@
 package auth0
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -24,6 +26,22 @@ func (f SecretProviderFunc) GetSecret(token *jwt.JSONWebToken) (interface{}, err
 	return f(token)
 }
 
+// SecretProviderContext is implemented by secret providers
+// able to stop retrieving the secret when ctx is done.
+type SecretProviderContext interface {
+	SecretProvider
+	GetSecretContext(ctx context.Context, token *jwt.JSONWebToken) (interface{}, error)
+}
+
+// getSecret retrieves the secret with the provided context when
+// supported by the provider, falling back to GetSecret otherwise.
+func getSecret(ctx context.Context, provider SecretProvider, token *jwt.JSONWebToken) (interface{}, error) {
+	if p, ok := provider.(SecretProviderContext); ok {
+		return p.GetSecretContext(ctx, token)
+	}
+	return provider.GetSecret(token)
+}
+
 // NewKeyProvider provide a simple passphrase key provider.
 func NewKeyProvider(key interface{}) SecretProvider {
 	return SecretProviderFunc(func(_ *jwt.JSONWebToken) (interface{}, error) {
@@ -43,6 +61,7 @@ type Configuration struct {
 	secretProvider SecretProvider
 	expectedClaims jwt.Expected
 	signIn         jose.SignatureAlgorithm
+	algorithms     []jose.SignatureAlgorithm
 }
 
 // NewConfiguration creates a configuration for server
@@ -62,90 +81,210 @@ func NewConfigurationTrustProvider(provider SecretProvider, audience []string, i
 	}
 }
 
+// NewConfigurationWithAlgorithms creates a configuration for server
+// accepting tokens signed with any of the algorithms, such as both RS256
+// and PS256 during a migration. HMAC algorithms are only accepted with
+// allowHMAC, and none is never accepted.
+func NewConfigurationWithAlgorithms(provider SecretProvider, audience []string, issuer string, algorithms []jose.SignatureAlgorithm, allowHMAC bool) (Configuration, error) {
+	if err := checkAlgorithms(algorithms, allowHMAC); err != nil {
+		return Configuration{}, err
+	}
+
+	return Configuration{
+		secretProvider: provider,
+		expectedClaims: jwt.Expected{Issuer: issuer, Audience: audience},
+		algorithms:     append([]jose.SignatureAlgorithm(nil), algorithms...),
+	}, nil
+}
+
+// checkAlgorithms checks that the algorithms can be allowed.
+func checkAlgorithms(algorithms []jose.SignatureAlgorithm, allowHMAC bool) error {
+	if len(algorithms) < 1 {
+		return fmt.Errorf("%w: no algorithm allowed", ErrInvalidAlgorithm)
+	}
+	for _, alg := range algorithms {
+		if isHMACAlgorithm(string(alg)) && !allowHMAC {
+			return fmt.Errorf("%w: %s requires allowing HMAC", ErrInvalidAlgorithm, alg)
+		}
+		if !isHMACAlgorithm(string(alg)) && !isAsymmetricAlgorithm(string(alg)) {
+			return fmt.Errorf("%w: %s is not supported", ErrInvalidAlgorithm, alg)
+		}
+	}
+	return nil
+}
+
 // JWTValidator helps middleware
 // to validate token
 type JWTValidator struct {
-	config    Configuration
-	extractor RequestTokenExtractor
+	config         Configuration
+	extractor      RequestTokenExtractor
+	leeway         time.Duration
+	clock          Clock
+	requiredClaims []string
 }
 
 // NewValidator creates a new
 // validator with the provided configuration.
 func NewValidator(config Configuration, extractor RequestTokenExtractor) *JWTValidator {
-	if extractor == nil {
-		extractor = RequestTokenExtractorFunc(FromHeader)
-	}
-	return &JWTValidator{config, extractor}
+	return newValidatorOptions([]Option{withConfiguration(config), WithExtractor(extractor)}).validator()
 }
 
 // ValidateRequest validates the token within
 // the http request.
-// A default leeway value of one minute is used to compare time values.
+// The leeway of the validator, one minute by default, is used to compare time values.
 func (v *JWTValidator) ValidateRequest(r *http.Request) (*jwt.JSONWebToken, error) {
-	return v.validateRequestWithLeeway(r, jwt.DefaultLeeway)
+	return v.validateRequestWithLeeway(r.Context(), r, v.leeway)
+}
+
+// ValidateRequestContext is like ValidateRequest but retrieving
+// the secret stops as soon as ctx is done.
+func (v *JWTValidator) ValidateRequestContext(ctx context.Context, r *http.Request) (*jwt.JSONWebToken, error) {
+	return v.validateRequestWithLeeway(ctx, r, v.leeway)
 }
 
 // ValidateRequestWithLeeway validates the token within
 // the http request.
 // The provided leeway value is used to compare time values.
 func (v *JWTValidator) ValidateRequestWithLeeway(r *http.Request, leeway time.Duration) (*jwt.JSONWebToken, error) {
-	return v.validateRequestWithLeeway(r, leeway)
+	return v.validateRequestWithLeeway(r.Context(), r, leeway)
 }
 
-func (v *JWTValidator) validateRequestWithLeeway(r *http.Request, leeway time.Duration) (*jwt.JSONWebToken, error) {
+func (v *JWTValidator) validateRequestWithLeeway(ctx context.Context, r *http.Request, leeway time.Duration, values ...interface{}) (*jwt.JSONWebToken, error) {
 	token, err := v.extractor.Extract(r)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := v.validateTokenWithLeeway(token, leeway); err != nil {
+	if err := v.validateTokenWithLeeway(ctx, token, leeway, values...); err != nil {
 		return nil, err
 	}
 
 	return token, nil
 }
 
+// ValidateRequestClaims validates the token within the http request and
+// unmarshalls its claims into values, verifying its signature only once.
+// It replaces calling ValidateRequest then Claims. A JWKClient finding
+// the key of a token without kid verifies it once more to do so.
+// The leeway of the validator, one minute by default, is used to compare time values.
+func (v *JWTValidator) ValidateRequestClaims(r *http.Request, values ...interface{}) (*jwt.JSONWebToken, error) {
+	return v.validateRequestWithLeeway(r.Context(), r, v.leeway, values...)
+}
+
 func (v *JWTValidator) ValidateToken(token *jwt.JSONWebToken) error {
-	return v.validateTokenWithLeeway(token, jwt.DefaultLeeway)
+	return v.validateTokenWithLeeway(context.Background(), token, v.leeway)
+}
+
+// ValidateTokenContext is like ValidateToken but retrieving
+// the secret stops as soon as ctx is done.
+func (v *JWTValidator) ValidateTokenContext(ctx context.Context, token *jwt.JSONWebToken) error {
+	return v.validateTokenWithLeeway(ctx, token, v.leeway)
+}
+
+// ValidateTokenClaims validates the token and unmarshalls its
+// claims into values, verifying its signature only once, like
+// ValidateRequestClaims.
+func (v *JWTValidator) ValidateTokenClaims(token *jwt.JSONWebToken, values ...interface{}) error {
+	return v.validateTokenWithLeeway(context.Background(), token, v.leeway, values...)
+}
+
+// ValidateTokenClaimsContext is like ValidateTokenClaims but retrieving
+// the secret stops as soon as ctx is done.
+func (v *JWTValidator) ValidateTokenClaimsContext(ctx context.Context, token *jwt.JSONWebToken, values ...interface{}) error {
+	return v.validateTokenWithLeeway(ctx, token, v.leeway, values...)
 }
 
 func (v *JWTValidator) ValidateTokenWithLeeway(token *jwt.JSONWebToken, leeway time.Duration) error {
-	return v.validateTokenWithLeeway(token, leeway)
+	return v.validateTokenWithLeeway(context.Background(), token, leeway)
 }
 
-func (v *JWTValidator) validateTokenWithLeeway(token *jwt.JSONWebToken, leeway time.Duration) error {
+// validateTokenWithLeeway validates the token and unmarshalls
+// its claims into values with the same signature verification.
+func (v *JWTValidator) validateTokenWithLeeway(ctx context.Context, token *jwt.JSONWebToken, leeway time.Duration, values ...interface{}) error {
 	if len(token.Headers) < 1 {
-		return ErrNoJWTHeaders
+		return newValidationError(ReasonMalformed, ErrNoJWTHeaders)
 	}
 
 	// trust secret provider when sig alg not configured and skip check
 	if v.config.signIn != "" {
 		header := token.Headers[0]
 		if header.Algorithm != string(v.config.signIn) {
-			return ErrInvalidAlgorithm
+			return newValidationError(ReasonAlgorithm, ErrInvalidAlgorithm)
 		}
 	}
+	if len(v.config.algorithms) > 0 && !containsAlgorithm(v.config.algorithms, token.Headers[0].Algorithm) {
+		return newValidationError(ReasonAlgorithm, ErrInvalidAlgorithm)
+	}
 
 	claims := jwt.Claims{}
-	key, err := v.config.secretProvider.GetSecret(token)
+	dests := append([]interface{}{&claims}, values...)
+	var present map[string]interface{}
+	if len(v.requiredClaims) > 0 {
+		dests = append(dests, &present)
+	}
+
+	key, err := getSecret(ctx, v.config.secretProvider, token)
 	if err != nil {
-		return err
+		return keyError(err)
+	}
+	if err = checkKeyAlgorithm(key, token.Headers[0].Algorithm); err != nil {
+		return newValidationError(ReasonAlgorithm, err)
 	}
 
-	if err = token.Claims(key, &claims); err != nil {
-		return err
+	if err = token.Claims(key, dests...); err != nil {
+		return signatureError(err)
 	}
 
-	expected := v.config.expectedClaims.WithTime(time.Now())
-	err = claims.ValidateWithLeeway(expected, leeway)
-	return err
+	expected := v.config.expectedClaims.WithTime(v.clock.Now())
+	if err = claims.ValidateWithLeeway(expected, leeway); err != nil {
+		return claimsError(err)
+	}
+	for _, name := range v.requiredClaims {
+		if present[name] == nil {
+			return newValidationError(ReasonClaim, fmt.Errorf("%w: %s", ErrMissingClaim, name))
+		}
+	}
+	return nil
 }
 
 // Claims unmarshall the claims of the provided token
 func (v *JWTValidator) Claims(token *jwt.JSONWebToken, values ...interface{}) error {
-	key, err := v.config.secretProvider.GetSecret(token)
+	return v.ClaimsContext(context.Background(), token, values...)
+}
+
+// ClaimsContext is like Claims but retrieving
+// the secret stops as soon as ctx is done.
+func (v *JWTValidator) ClaimsContext(ctx context.Context, token *jwt.JSONWebToken, values ...interface{}) error {
+	if len(token.Headers) < 1 {
+		return newValidationError(ReasonMalformed, ErrNoJWTHeaders)
+	}
+
+	key, err := getSecret(ctx, v.config.secretProvider, token)
 	if err != nil {
-		return err
+		return keyError(err)
+	}
+	if err = checkKeyAlgorithm(key, token.Headers[0].Algorithm); err != nil {
+		return newValidationError(ReasonAlgorithm, err)
+	}
+	if err = token.Claims(key, values...); err != nil {
+		return signatureError(err)
+	}
+	return nil
+}
+
+func isHMACAlgorithm(alg string) bool {
+	switch jose.SignatureAlgorithm(alg) {
+	case jose.HS256, jose.HS384, jose.HS512:
+		return true
+	}
+	return false
+}
+
+func containsAlgorithm(algorithms []jose.SignatureAlgorithm, algorithm string) bool {
+	for _, alg := range algorithms {
+		if string(alg) == algorithm {
+			return true
+		}
 	}
-	return token.Claims(key, values...)
-}
\ No newline at end of file
+	return false
+}
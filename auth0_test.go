@@ -1,6 +1,7 @@
 package auth0
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -133,7 +134,7 @@ func TestValidateRequestAndClaims(t *testing.T) {
 				jose.RS256,
 				defaultSecretRS256,
 			),
-			expectedErrorMsg: "error in cryptographic primitive",
+			expectedErrorMsg: "key algorithm does not match the token algorithm",
 		},
 		{
 			name: "fail - invalid config secret provider",
@@ -400,7 +401,7 @@ func TestValidateRequestAndClaimsWithLeeway(t *testing.T) {
 				defaultSecretRS256,
 			),
 			leeway:           jwt.DefaultLeeway,
-			expectedErrorMsg: "error in cryptographic primitive",
+			expectedErrorMsg: "key algorithm does not match the token algorithm",
 		},
 		{
 			name: "fail - invalid config secret provider",
@@ -539,3 +540,207 @@ func TestValidateRequestAndClaimsWithLeeway(t *testing.T) {
 		})
 	}
 }
+
+type contextSecretProvider struct {
+	SecretProvider
+	ctx context.Context
+}
+
+func (p *contextSecretProvider) GetSecretContext(ctx context.Context, token *jwt.JSONWebToken) (interface{}, error) {
+	p.ctx = ctx
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.GetSecret(token)
+}
+
+func TestValidateRequestContext(t *testing.T) {
+	provider := &contextSecretProvider{SecretProvider: defaultSecretProvider}
+	configuration := NewConfiguration(provider, defaultAudience, defaultIssuer, jose.HS256)
+	token := getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.HS256, defaultSecret)
+	validator, req := genTestConfiguration(configuration, token)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	_, err := validator.ValidateRequestContext(ctx, req)
+	if err != nil {
+		t.Errorf("Validation should not have failed with error, but got: " + err.Error())
+	}
+	if provider.ctx == nil || provider.ctx.Value(ctxKey{}) != "value" {
+		t.Errorf("The context should have been passed to the secret provider")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = validator.ValidateRequestContext(cancelled, req)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Validation should have failed with context.Canceled, but got: %v", err)
+	}
+
+	_, err = validator.ValidateRequest(req.WithContext(cancelled))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Validation should use the request context, but got: %v", err)
+	}
+
+	err = validator.ValidateTokenContext(cancelled, getTestTokenWithKid(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.HS256, defaultSecret, ""))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Validation should have failed with context.Canceled, but got: %v", err)
+	}
+}
+
+func TestValidateRequestClaims(t *testing.T) {
+	provider := &countingSecretProvider{SecretProvider: defaultSecretProvider}
+	configuration := NewConfiguration(provider, defaultAudience, defaultIssuer, jose.HS256)
+	token := getTestTokenWithClaims(jose.HS256, defaultSecret, defaultTestClaims(), map[string]interface{}{"scope": "read:news"})
+	validator, req := genTestConfiguration(configuration, token)
+
+	claims := jwt.Claims{}
+	custom := map[string]interface{}{}
+	validated, err := validator.ValidateRequestClaims(req, &claims, &custom)
+	if err != nil {
+		t.Fatalf("Validation should not have failed with error, but got: %v", err)
+	}
+	if validated == nil {
+		t.Errorf("The validated token should have been returned")
+	}
+	if provider.calls != 1 {
+		t.Errorf("The signature should have been verified once, but the secret was retrieved %d times", provider.calls)
+	}
+	if claims.Issuer != defaultIssuer || custom["scope"] != "read:news" {
+		t.Errorf("Claims should have been unmarshalled, but got: %v, %v", claims, custom)
+	}
+
+	err = validator.ValidateTokenClaims(validated, &custom)
+	if err != nil {
+		t.Errorf("Validation should not have failed with error, but got: " + err.Error())
+	}
+
+	expired := getTestToken(defaultAudience, defaultIssuer, time.Now().Add(-24*time.Hour), jose.HS256, defaultSecret)
+	validator, req = genTestConfiguration(configuration, expired)
+	_, err = validator.ValidateRequestClaims(req, &custom)
+	if err == nil || !strings.Contains(err.Error(), "token is expired (exp)") {
+		t.Errorf("Validation should have failed with an expired token error, but got: %v", err)
+	}
+}
+
+func benchmarkValidator(b *testing.B) (*JWTValidator, *http.Request) {
+	configuration := NewConfiguration(defaultSecretProviderRS256, defaultAudience, defaultIssuer, jose.RS256)
+	token := getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.RS256, defaultSecretRS256)
+	return genTestConfiguration(configuration, token)
+}
+
+func BenchmarkValidateRequestThenClaims(b *testing.B) {
+	validator, req := benchmarkValidator(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		token, err := validator.ValidateRequest(req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		claims := map[string]interface{}{}
+		if err := validator.Claims(token, &claims); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidateRequestClaims(b *testing.B) {
+	validator, req := benchmarkValidator(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		claims := map[string]interface{}{}
+		if _, err := validator.ValidateRequestClaims(req, &claims); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestNewConfigurationWithAlgorithms(t *testing.T) {
+	tests := []struct {
+		name          string
+		algorithms    []jose.SignatureAlgorithm
+		allowHMAC     bool
+		expectedError string
+	}{
+		{name: "asymmetric", algorithms: []jose.SignatureAlgorithm{jose.RS256, jose.PS256, jose.ES256, jose.EdDSA}},
+		{name: "HMAC allowed", algorithms: []jose.SignatureAlgorithm{jose.RS256, jose.HS256}, allowHMAC: true},
+		{name: "HMAC not allowed", algorithms: []jose.SignatureAlgorithm{jose.RS256, jose.HS256}, expectedError: "HS256 requires allowing HMAC"},
+		{name: "none", algorithms: []jose.SignatureAlgorithm{"none"}, allowHMAC: true, expectedError: "none is not supported"},
+		{name: "unknown", algorithms: []jose.SignatureAlgorithm{"RS1"}, expectedError: "RS1 is not supported"},
+		{name: "empty", expectedError: "no algorithm allowed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewConfigurationWithAlgorithms(defaultSecretProvider, defaultAudience, defaultIssuer, test.algorithms, test.allowHMAC)
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Configuration should not have failed with error, but got: " + err.Error())
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidAlgorithm) || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("Configuration should have failed with error with substring: %s, but got: %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateRequestWithAlgorithms(t *testing.T) {
+	provider := NewKeyProvider(defaultSecretRS256.Public().Key)
+	configuration, err := NewConfigurationWithAlgorithms(provider, defaultAudience, defaultIssuer, []jose.SignatureAlgorithm{jose.RS256, jose.PS256}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacConfiguration, err := NewConfigurationWithAlgorithms(defaultSecretProvider, defaultAudience, defaultIssuer, []jose.SignatureAlgorithm{jose.RS256, jose.HS256}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		configuration Configuration
+		token         string
+		expectedError error
+	}{
+		{
+			name:          "RS256",
+			configuration: configuration,
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.RS256, defaultSecretRS256.Key),
+		},
+		{
+			name:          "PS256",
+			configuration: configuration,
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.PS256, defaultSecretRS256.Key),
+		},
+		{
+			name:          "RS512 not allowed",
+			configuration: configuration,
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.RS512, defaultSecretRS256.Key),
+			expectedError: ErrInvalidAlgorithm,
+		},
+		{
+			name:          "HS256 not allowed",
+			configuration: configuration,
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+			expectedError: ErrInvalidAlgorithm,
+		},
+		{
+			name:          "HS256 allowed",
+			configuration: hmacConfiguration,
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator, req := genTestConfiguration(test.configuration, test.token)
+
+			_, err := validator.ValidateRequest(req)
+			if test.expectedError == nil && err != nil {
+				t.Errorf("Validation should not have failed with error, but got: " + err.Error())
+			}
+			if test.expectedError != nil && !errors.Is(err, test.expectedError) {
+				t.Errorf("Validation should have failed with %v, but got: %v", test.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package auth0
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInsufficientScope is wrapped by the AuthorizationError returned
+	// when the scope claim of the token lacks required scopes.
+	ErrInsufficientScope = errors.New("insufficient scope")
+	// ErrInsufficientPermissions is wrapped by the AuthorizationError returned
+	// when the permissions claim of the token lacks required permissions.
+	ErrInsufficientPermissions = errors.New("insufficient permissions")
+)
+
+// AccessClaims holds the claims Auth0 uses to grant access to an API:
+// the space-delimited scope claim and the RBAC permissions claim.
+type AccessClaims struct {
+	Scope       string   `json:"scope,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Scopes returns the scopes of the scope claim.
+func (c AccessClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports whether the scope claim contains scope.
+func (c AccessClaims) HasScope(scope string) bool {
+	return containsString(c.Scopes(), scope)
+}
+
+// HasPermission reports whether the permissions claim contains permission.
+func (c AccessClaims) HasPermission(permission string) bool {
+	return containsString(c.Permissions, permission)
+}
+
+// AuthorizationError is returned when a token is valid
+// but does not grant the required access.
+type AuthorizationError struct {
+	// Claim is the claim lacking values, either "scope" or "permissions".
+	Claim string
+	// Required are the values required by the failing Requirement.
+	Required []string
+	// Missing are the required values absent from the claim.
+	Missing []string
+
+	err error
+}
+
+func (e *AuthorizationError) Error() string {
+	return e.err.Error() + ", missing: " + strings.Join(e.Missing, " ")
+}
+
+// Unwrap returns either ErrInsufficientScope or ErrInsufficientPermissions.
+func (e *AuthorizationError) Unwrap() error {
+	return e.err
+}
+
+// Requirement checks that the access claims
+// of a token grant the required access.
+type Requirement func(claims AccessClaims) error
+
+// RequireAllScopes requires the scope claim to contain all the scopes.
+func RequireAllScopes(scopes ...string) Requirement {
+	return func(claims AccessClaims) error {
+		missing := missingValues(claims.Scopes(), scopes)
+		if len(missing) > 0 {
+			return &AuthorizationError{Claim: "scope", Required: scopes, Missing: missing, err: ErrInsufficientScope}
+		}
+		return nil
+	}
+}
+
+// RequireAnyScope requires the scope claim to contain at least one of the scopes.
+func RequireAnyScope(scopes ...string) Requirement {
+	return func(claims AccessClaims) error {
+		for _, scope := range scopes {
+			if claims.HasScope(scope) {
+				return nil
+			}
+		}
+		return &AuthorizationError{Claim: "scope", Required: scopes, Missing: scopes, err: ErrInsufficientScope}
+	}
+}
+
+// RequirePermissions requires the permissions claim to contain all the permissions.
+func RequirePermissions(permissions ...string) Requirement {
+	return func(claims AccessClaims) error {
+		missing := missingValues(claims.Permissions, permissions)
+		if len(missing) > 0 {
+			return &AuthorizationError{Claim: "permissions", Required: permissions, Missing: missing, err: ErrInsufficientPermissions}
+		}
+		return nil
+	}
+}
+
+// Authorize checks the access claims against all the
+// requirements and returns the first failure.
+func Authorize(claims AccessClaims, requirements ...Requirement) error {
+	for _, requirement := range requirements {
+		if err := requirement(claims); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func missingValues(values []string, required []string) []string {
+	var missing []string
+	for _, value := range required {
+		if !containsString(values, value) {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
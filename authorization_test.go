@@ -0,0 +1,145 @@
+package auth0
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestAuthorize(t *testing.T) {
+	claims := AccessClaims{
+		Scope:       "openid read:news write:news",
+		Permissions: []string{"read:users", "delete:users"},
+	}
+
+	tests := []struct {
+		name            string
+		requirements    []Requirement
+		expectedErr     error
+		expectedMissing []string
+	}{
+		{
+			name: "pass - no requirement",
+		},
+		{
+			name:         "pass - all scopes",
+			requirements: []Requirement{RequireAllScopes("read:news", "write:news")},
+		},
+		{
+			name:            "fail - all scopes",
+			requirements:    []Requirement{RequireAllScopes("read:news", "admin", "write:news", "delete:news")},
+			expectedErr:     ErrInsufficientScope,
+			expectedMissing: []string{"admin", "delete:news"},
+		},
+		{
+			name:         "pass - any scope",
+			requirements: []Requirement{RequireAnyScope("admin", "write:news")},
+		},
+		{
+			name:            "fail - any scope",
+			requirements:    []Requirement{RequireAnyScope("admin", "delete:news")},
+			expectedErr:     ErrInsufficientScope,
+			expectedMissing: []string{"admin", "delete:news"},
+		},
+		{
+			name:         "fail - any scope without scopes",
+			requirements: []Requirement{RequireAnyScope()},
+			expectedErr:  ErrInsufficientScope,
+		},
+		{
+			name:            "fail - scope prefix is not a scope",
+			requirements:    []Requirement{RequireAllScopes("read")},
+			expectedErr:     ErrInsufficientScope,
+			expectedMissing: []string{"read"},
+		},
+		{
+			name:         "pass - permissions",
+			requirements: []Requirement{RequirePermissions("delete:users", "read:users")},
+		},
+		{
+			name:            "fail - permissions",
+			requirements:    []Requirement{RequirePermissions("read:users", "create:users")},
+			expectedErr:     ErrInsufficientPermissions,
+			expectedMissing: []string{"create:users"},
+		},
+		{
+			name:            "fail - first failing requirement",
+			requirements:    []Requirement{RequireAllScopes("read:news"), RequirePermissions("create:users"), RequireAllScopes("admin")},
+			expectedErr:     ErrInsufficientPermissions,
+			expectedMissing: []string{"create:users"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Authorize(claims, test.requirements...)
+			if test.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, test.expectedErr))
+			var authorizationErr *AuthorizationError
+			if assert.True(t, errors.As(err, &authorizationErr)) {
+				assert.Equal(t, test.expectedMissing, authorizationErr.Missing)
+			}
+		})
+	}
+}
+
+func TestAuthorizeEmptyClaims(t *testing.T) {
+	err := Authorize(AccessClaims{}, RequireAllScopes("read:news"))
+	assert.True(t, errors.Is(err, ErrInsufficientScope))
+	assert.Equal(t, "insufficient scope, missing: read:news", err.Error())
+
+	err = Authorize(AccessClaims{}, RequirePermissions("read:news"))
+	assert.True(t, errors.Is(err, ErrInsufficientPermissions))
+}
+
+func TestMiddlewareRequirements(t *testing.T) {
+	configuration := NewConfiguration(defaultSecretProvider, defaultAudience, defaultIssuer, jose.HS256)
+	validator := NewValidator(configuration, nil)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	token := getTestTokenWithClaims(jose.HS256, defaultSecret, defaultTestClaims(), AccessClaims{
+		Scope:       "read:news",
+		Permissions: []string{"read:users"},
+	})
+
+	tests := []struct {
+		name               string
+		requirements       []Requirement
+		expectedStatusCode int
+	}{
+		{
+			name:               "pass - scope and permission",
+			requirements:       []Requirement{RequireAllScopes("read:news"), RequirePermissions("read:users")},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "fail - missing scope",
+			requirements:       []Requirement{RequireAnyScope("write:news")},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "fail - missing permission",
+			requirements:       []Requirement{RequirePermissions("delete:users")},
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://localhost", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			validator.MiddlewareWithOptions(next, MiddlewareOptions{Requirements: test.requirements}).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedStatusCode, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package auth0
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Error codes of the WWW-Authenticate response header, see RFC 6750 section 3.1.
+const (
+	BearerErrorInvalidRequest    = "invalid_request"
+	BearerErrorInvalidToken      = "invalid_token"
+	BearerErrorInsufficientScope = "insufficient_scope"
+)
+
+// NewBearerErrorHandler returns an ErrorHandler
+// answering with WriteBearerError in realm.
+func NewBearerErrorHandler(realm string) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		WriteBearerError(w, realm, err)
+	}
+}
+
+// WriteBearerError answers a request which failed validation as
+// described by RFC 6750, with a WWW-Authenticate header such as:
+//
+//	Bearer realm="api", error="invalid_token", error_description="token expired"
+//
+// Requests without a token get a 401 Unauthorized status without error code,
+// invalid tokens a 401 Unauthorized status with the invalid_token code and
+// tokens lacking scopes or permissions a 403 Forbidden status with the
+// insufficient_scope code along with the required scopes. When the keys
+// verifying the tokens cannot be fetched, the status is 503 Service
+// Unavailable without error code.
+// The realm is omitted when empty.
+func WriteBearerError(w http.ResponseWriter, realm string, err error) {
+	status, params := bearerError(err)
+
+	challenge := "Bearer"
+	if realm != "" {
+		params = append([]string{"realm", realm}, params...)
+	}
+	for i := 0; i < len(params); i += 2 {
+		if i > 0 {
+			challenge += ","
+		}
+		challenge += " " + params[i] + `="` + quotableBearerValue(params[i+1]) + `"`
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// bearerError returns the status code and the
+// challenge parameters describing err.
+func bearerError(err error) (int, []string) {
+	var authorizationErr *AuthorizationError
+	var validationErr *ValidationError
+
+	switch {
+	case errors.Is(err, ErrTokenNotFound):
+		return http.StatusUnauthorized, nil
+	case errors.Is(err, ErrNilRequest):
+		return http.StatusBadRequest, []string{"error", BearerErrorInvalidRequest}
+	case errors.As(err, &authorizationErr):
+		params := []string{"error", BearerErrorInsufficientScope, "error_description", authorizationErr.Error()}
+		if authorizationErr.Claim == "scope" {
+			params = append(params, "scope", strings.Join(authorizationErr.Required, " "))
+		}
+		return http.StatusForbidden, params
+	case errors.As(err, &validationErr) && validationErr.Reason == ReasonKeyFetchFailed:
+		// the token may be valid, the keys verifying it are unavailable
+		return http.StatusServiceUnavailable, nil
+	case errors.As(err, &validationErr):
+		// the reason only, the cause may disclose details of the configuration
+		return http.StatusUnauthorized, []string{"error", BearerErrorInvalidToken, "error_description", validationErr.Reason.String()}
+	default:
+		return http.StatusUnauthorized, []string{"error", BearerErrorInvalidToken}
+	}
+}
+
+// quotableBearerValue drops the characters which are not
+// allowed in the quoted values of the challenge.
+func quotableBearerValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,116 @@
+package auth0
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestWriteBearerError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		realm        string
+		err          error
+		status       int
+		authenticate string
+	}{
+		{
+			name:         "token not found",
+			realm:        "api",
+			err:          ErrTokenNotFound,
+			status:       http.StatusUnauthorized,
+			authenticate: `Bearer realm="api"`,
+		},
+		{
+			name:         "token not found without realm",
+			err:          ErrTokenNotFound,
+			status:       http.StatusUnauthorized,
+			authenticate: `Bearer`,
+		},
+		{
+			name:         "nil request",
+			realm:        "api",
+			err:          ErrNilRequest,
+			status:       http.StatusBadRequest,
+			authenticate: `Bearer realm="api", error="invalid_request"`,
+		},
+		{
+			name:         "expired token",
+			realm:        "api",
+			err:          &ValidationError{Reason: ReasonExpired, Err: errors.New("cause")},
+			status:       http.StatusUnauthorized,
+			authenticate: `Bearer realm="api", error="invalid_token", error_description="token expired"`,
+		},
+		{
+			name:         "key fetch failed",
+			realm:        "api",
+			err:          &ValidationError{Reason: ReasonKeyFetchFailed, Err: errors.New("cause")},
+			status:       http.StatusServiceUnavailable,
+			authenticate: `Bearer realm="api"`,
+		},
+		{
+			name:         "unknown error",
+			err:          errors.New("unknown"),
+			status:       http.StatusUnauthorized,
+			authenticate: `Bearer error="invalid_token"`,
+		},
+		{
+			name:         "insufficient scope",
+			realm:        "api",
+			err:          RequireAllScopes("read:news", "write:news")(AccessClaims{Scope: "read:news"}),
+			status:       http.StatusForbidden,
+			authenticate: `Bearer realm="api", error="insufficient_scope", error_description="insufficient scope, missing: write:news", scope="read:news write:news"`,
+		},
+		{
+			name:         "insufficient permissions",
+			realm:        "api",
+			err:          RequirePermissions("read:users")(AccessClaims{}),
+			status:       http.StatusForbidden,
+			authenticate: `Bearer realm="api", error="insufficient_scope", error_description="insufficient permissions, missing: read:users"`,
+		},
+		{
+			name:         "quoted values",
+			realm:        "my \"api\"\\\n",
+			err:          ErrTokenNotFound,
+			status:       http.StatusUnauthorized,
+			authenticate: `Bearer realm="my api"`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteBearerError(rec, test.realm, test.err)
+
+			assert.Equal(t, test.status, rec.Code)
+			assert.Equal(t, test.authenticate, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestMiddlewareBearerErrorHandler(t *testing.T) {
+	configuration := NewConfiguration(defaultSecretProvider, defaultAudience, defaultIssuer, jose.HS256)
+	validator := NewValidator(configuration, nil)
+	handler := validator.MiddlewareWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), MiddlewareOptions{
+		ErrorHandler: NewBearerErrorHandler("news"),
+		Requirements: []Requirement{RequireAnyScope("read:news")},
+	})
+
+	req := httptest.NewRequest("GET", "http://localhost", nil)
+	req.Header.Set("Authorization", "Bearer "+getTestToken(defaultAudience, defaultIssuer, time.Now().Add(-time.Hour), jose.HS256, defaultSecret))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer realm="news", error="invalid_token", error_description="token expired"`, rec.Header().Get("WWW-Authenticate"))
+
+	req.Header.Set("Authorization", "Bearer "+getTestToken(defaultAudience, defaultIssuer, time.Now().Add(time.Hour), jose.HS256, defaultSecret))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, `Bearer realm="news", error="insufficient_scope", error_description="insufficient scope, missing: read:news", scope="read:news"`, rec.Header().Get("WWW-Authenticate"))
+}
@@ -0,0 +1,63 @@
+package auth0
+
+import (
+	"net/http"
+	"strings"
+
+	"gopkg.in/square/go-jose.v2/json"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// Auth0Claims holds the registered claims along with
+// the claims commonly found in tokens issued by Auth0.
+type Auth0Claims struct {
+	jwt.Claims
+	AccessClaims
+	AuthorizedParty string `json:"azp,omitempty"`
+	GrantType       string `json:"gty,omitempty"`
+	OrgID           string `json:"org_id,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface,
+// keeping the custom claims for DecodeNamespace.
+func (c *Auth0Claims) UnmarshalJSON(data []byte) error {
+	type claims Auth0Claims
+	if err := json.Unmarshal(data, (*claims)(c)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &c.raw)
+}
+
+// DecodeNamespace unmarshalls the claims which names start with the
+// namespace into v, with the namespace trimmed from their names.
+// With the "https://example.com/" namespace, the claim
+// "https://example.com/roles" is unmarshalled as "roles".
+func (c *Auth0Claims) DecodeNamespace(namespace string, v interface{}) error {
+	// RawMessage only implements json.Marshaler on its pointer
+	namespaced := map[string]*json.RawMessage{}
+	for name, value := range c.raw {
+		if strings.HasPrefix(name, namespace) && len(name) > len(namespace) {
+			value := value
+			namespaced[strings.TrimPrefix(name, namespace)] = &value
+		}
+	}
+
+	data, err := json.Marshal(namespaced)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ValidateRequestAuth0Claims validates the token within the http request
+// and returns its claims, verifying its signature only once.
+// The leeway of the validator, one minute by default, is used to compare time values.
+func (v *JWTValidator) ValidateRequestAuth0Claims(r *http.Request) (*Auth0Claims, error) {
+	claims := &Auth0Claims{}
+	if _, err := v.ValidateRequestClaims(r, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,119 @@
+package auth0
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+type countingSecretProvider struct {
+	SecretProvider
+	calls uint64
+}
+
+func (p *countingSecretProvider) GetSecret(token *jwt.JSONWebToken) (interface{}, error) {
+	atomic.AddUint64(&p.calls, 1)
+	return p.SecretProvider.GetSecret(token)
+}
+
+var testAuth0ClaimsPayload = map[string]interface{}{
+	"scope":                            "read:news write:news",
+	"permissions":                      []string{"read:users"},
+	"azp":                              "client-id",
+	"gty":                              "client-credentials",
+	"org_id":                           "org_123",
+	"https://example.com/roles":        []string{"admin", "editor"},
+	"https://example.com/app_metadata": map[string]interface{}{"plan": "premium"},
+	"https://other.com/roles":          []string{"viewer"},
+	"https://example.com/":             "not namespaced",
+}
+
+func TestValidateRequestAuth0Claims(t *testing.T) {
+	provider := &countingSecretProvider{SecretProvider: defaultSecretProvider}
+	configuration := NewConfiguration(provider, defaultAudience, defaultIssuer, jose.HS256)
+	token := getTestTokenWithClaims(jose.HS256, defaultSecret, defaultTestClaims(), testAuth0ClaimsPayload)
+	validator, req := genTestConfiguration(configuration, token)
+
+	claims, err := validator.ValidateRequestAuth0Claims(req)
+	if err != nil {
+		t.Fatalf("Validation should not have failed with error, but got: %v", err)
+	}
+	assert.Equal(t, uint64(1), provider.calls, "the secret should be retrieved only once")
+
+	assert.Equal(t, defaultIssuer, claims.Issuer)
+	assert.Equal(t, jwt.Audience(defaultAudience), claims.Audience)
+	assert.Equal(t, "read:news write:news", claims.Scope)
+	assert.Equal(t, []string{"read:users"}, claims.Permissions)
+	assert.Equal(t, "client-id", claims.AuthorizedParty)
+	assert.Equal(t, "client-credentials", claims.GrantType)
+	assert.Equal(t, "org_123", claims.OrgID)
+	assert.True(t, claims.HasScope("write:news"))
+
+	custom := struct {
+		Roles       []string `json:"roles"`
+		AppMetadata struct {
+			Plan string `json:"plan"`
+		} `json:"app_metadata"`
+	}{}
+	assert.NoError(t, claims.DecodeNamespace("https://example.com/", &custom))
+	assert.Equal(t, []string{"admin", "editor"}, custom.Roles)
+	assert.Equal(t, "premium", custom.AppMetadata.Plan)
+
+	all := map[string]interface{}{}
+	assert.NoError(t, claims.DecodeNamespace("https://example.com/", &all))
+	assert.Len(t, all, 2)
+}
+
+func TestValidateRequestAuth0ClaimsInvalid(t *testing.T) {
+	configuration := NewConfiguration(defaultSecretProvider, defaultAudience, defaultIssuer, jose.HS256)
+	token := getTestToken(defaultAudience, defaultIssuer, time.Now().Add(-24*time.Hour), jose.HS256, defaultSecret)
+	validator, req := genTestConfiguration(configuration, token)
+
+	claims, err := validator.ValidateRequestAuth0Claims(req)
+	assert.Nil(t, claims)
+	if err == nil || !strings.Contains(err.Error(), "token is expired (exp)") {
+		t.Errorf("Validation should have failed with an expired token error, but got: %v", err)
+	}
+}
+
+func TestDecodeNamespaceWithoutClaims(t *testing.T) {
+	claims := Auth0Claims{}
+	custom := struct {
+		Roles []string `json:"roles"`
+	}{}
+	assert.NoError(t, claims.DecodeNamespace("https://example.com/", &custom))
+	assert.Empty(t, custom.Roles)
+}
+
+func TestMiddlewareAuth0Claims(t *testing.T) {
+	configuration := NewConfiguration(defaultSecretProvider, defaultAudience, defaultIssuer, jose.HS256)
+	validator := NewValidator(configuration, nil)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := Auth0ClaimsFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "org_123", claims.OrgID)
+		assert.Equal(t, defaultIssuer, claims.Issuer)
+
+		roles := struct {
+			Roles []string `json:"roles"`
+		}{}
+		assert.NoError(t, claims.DecodeNamespace("https://example.com/", &roles))
+		assert.Equal(t, []string{"admin", "editor"}, roles.Roles)
+	})
+
+	token := getTestTokenWithClaims(jose.HS256, defaultSecret, defaultTestClaims(), testAuth0ClaimsPayload)
+	req := httptest.NewRequest("GET", "http://localhost", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	validator.Middleware(next).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
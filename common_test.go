@@ -9,6 +9,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	jose "gopkg.in/square/go-jose.v2"
@@ -33,6 +36,28 @@ var (
 	defaultSecretProviderES384 = NewKeyProvider(defaultSecretES384.Public())
 )
 
+// fakeClock is a Clock which time only moves with Advance.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
 func genRSASSAJWK(sigAlg jose.SignatureAlgorithm, kid string) jose.JSONWebKey {
 	var bits int
 	if sigAlg == jose.RS256 {
@@ -149,3 +174,81 @@ func genNewTestServer(genJWKS bool) (JWKClientOptions, *jwt.JSONWebToken, *jwt.J
 	}))
 	return JWKClientOptions{URI: ts.URL}, tokenRS256, tokenES384, err
 }
+
+// jwksTestHandler is called by the server of genJWKSTestServer with the
+// number of each request, before the keys are served. It may set response
+// headers or wait, and reports whether it answered the request itself.
+type jwksTestHandler func(call uint64, w http.ResponseWriter, r *http.Request) bool
+
+// genJWKSTestServer serves keys, after calling handle if not nil,
+// and counts the requests.
+func genJWKSTestServer(t *testing.T, handle jwksTestHandler, keys ...jose.JSONWebKey) (*httptest.Server, *uint64) {
+	value, err := json.Marshal(JWKS{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var counter uint64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := atomic.AddUint64(&counter, 1)
+		if handle != nil && handle(call, w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(value)
+	}))
+	return ts, &counter
+}
+
+// genTestKeys returns the public keys keyRS256 and keyES384.
+func genTestKeys() []jose.JSONWebKey {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	jsonWebKeyES384 := genECDSAJWK(jose.ES384, "keyES384")
+	return []jose.JSONWebKey{jsonWebKeyRS256.Public(), jsonWebKeyES384.Public()}
+}
+
+// failWith answers with the status stored in failing, unless it is zero.
+func failWith(failing *int32) jwksTestHandler {
+	return func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+		if code := atomic.LoadInt32(failing); code != 0 {
+			w.WriteHeader(int(code))
+			return true
+		}
+		return false
+	}
+}
+
+// waitForRelease holds each request until release is closed.
+func waitForRelease(release <-chan struct{}) jwksTestHandler {
+	return func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+		<-release
+		return false
+	}
+}
+
+func getTestTokenWithClaims(alg jose.SignatureAlgorithm, key interface{}, claims ...interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		panic(err)
+	}
+
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+
+	raw, err := builder.CompactSerialize()
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+func defaultTestClaims() jwt.Claims {
+	return jwt.Claims{
+		Issuer:   defaultIssuer,
+		Audience: defaultAudience,
+		IssuedAt: jwt.NewNumericDate(time.Now().UTC()),
+		Expiry:   jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+	}
+}
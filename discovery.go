@@ -0,0 +1,114 @@
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// DiscoveryPath is the path of the OpenID Connect discovery
+// document, relative to the issuer URL.
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+var (
+	// ErrInvalidDiscoveryDocument is returned when the OpenID Connect
+	// discovery document cannot be used to validate tokens.
+	ErrInvalidDiscoveryDocument = errors.New("invalid OpenID Connect discovery document")
+	// ErrIssuerMismatch is returned when the issuer of the OpenID Connect
+	// discovery document is not the requested one.
+	ErrIssuerMismatch = errors.New("issuer of the discovery document does not match")
+)
+
+// DiscoveryDocument holds the OpenID Connect provider
+// metadata needed to validate tokens.
+type DiscoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// NewConfigurationFromDiscovery fetches the OpenID Connect discovery document
+// of the issuer and creates a JWKClient for its jwks_uri along with a
+// Configuration expecting its issuer and restricted to the asymmetric
+// algorithms of id_token_signing_alg_values_supported.
+// The issuer is compared regardless of any trailing slash, and the one
+// of the discovery document is the one expected in tokens.
+// options.Client is used for the discovery request too, options.URI is ignored.
+func NewConfigurationFromDiscovery(ctx context.Context, issuer string, audience []string, options JWKClientOptions) (Configuration, *JWKClient, error) {
+	client := options.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	document, err := fetchDiscoveryDocument(ctx, client, issuer)
+	if err != nil {
+		return Configuration{}, nil, err
+	}
+
+	if strings.TrimSuffix(document.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return Configuration{}, nil, ErrIssuerMismatch
+	}
+	if document.JWKSURI == "" {
+		return Configuration{}, nil, fmt.Errorf("%w: missing jwks_uri", ErrInvalidDiscoveryDocument)
+	}
+
+	var algorithms []jose.SignatureAlgorithm
+	for _, alg := range document.IDTokenSigningAlgValuesSupported {
+		// HMAC keys are never published in a JWKS
+		if isAsymmetricAlgorithm(alg) {
+			algorithms = append(algorithms, jose.SignatureAlgorithm(alg))
+		}
+	}
+	if len(algorithms) < 1 {
+		return Configuration{}, nil, fmt.Errorf("%w: no supported signing algorithm", ErrInvalidDiscoveryDocument)
+	}
+
+	options.URI = document.JWKSURI
+	jwkClient := NewJWKClient(options, nil)
+
+	configuration := Configuration{
+		secretProvider: jwkClient,
+		expectedClaims: jwt.Expected{Issuer: document.Issuer, Audience: audience},
+		algorithms:     algorithms,
+	}
+	return configuration, jwkClient, nil
+}
+
+func fetchDiscoveryDocument(ctx context.Context, client *http.Client, issuer string) (DiscoveryDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(issuer, "/")+DiscoveryPath, nil)
+	if err != nil {
+		return DiscoveryDocument{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return DiscoveryDocument{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return DiscoveryDocument{}, fmt.Errorf("%w: unexpected status code %d", ErrInvalidDiscoveryDocument, resp.StatusCode)
+	}
+
+	var document DiscoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return DiscoveryDocument{}, fmt.Errorf("%w: %v", ErrInvalidDiscoveryDocument, err)
+	}
+	return document, nil
+}
+
+func isAsymmetricAlgorithm(alg string) bool {
+	switch jose.SignatureAlgorithm(alg) {
+	case jose.RS256, jose.RS384, jose.RS512,
+		jose.PS256, jose.PS384, jose.PS512,
+		jose.ES256, jose.ES384, jose.ES512,
+		jose.EdDSA:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// genDiscoveryTestServer serves a discovery document built by document
+// from the server URL, and a JWKS holding the keys.
+func genDiscoveryTestServer(document func(url string) string, keys ...jose.JSONWebKey) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case DiscoveryPath:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintln(w, document(ts.URL))
+		case "/jwks.json":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(JWKS{Keys: keys})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return ts
+}
+
+func validDiscoveryDocument(url string) string {
+	return fmt.Sprintf(`{
+		"issuer": "%s/",
+		"jwks_uri": "%s/jwks.json",
+		"id_token_signing_alg_values_supported": ["HS256", "RS256"]
+	}`, url, url)
+}
+
+func TestNewConfigurationFromDiscovery(t *testing.T) {
+	tests := []struct {
+		name             string
+		document         func(url string) string
+		issuerSuffix     string
+		expectedErrorMsg string
+	}{
+		{
+			name:         "pass - issuer with trailing slash",
+			document:     validDiscoveryDocument,
+			issuerSuffix: "/",
+		},
+		{
+			name:         "pass - issuer without trailing slash",
+			document:     validDiscoveryDocument,
+			issuerSuffix: "",
+		},
+		{
+			name: "fail - issuer mismatch",
+			document: func(url string) string {
+				return `{"issuer": "https://evil.example.com/", "jwks_uri": "` + url + `/jwks.json", "id_token_signing_alg_values_supported": ["RS256"]}`
+			},
+			expectedErrorMsg: "issuer of the discovery document does not match",
+		},
+		{
+			name: "fail - missing jwks_uri",
+			document: func(url string) string {
+				return `{"issuer": "` + url + `/", "id_token_signing_alg_values_supported": ["RS256"]}`
+			},
+			expectedErrorMsg: "missing jwks_uri",
+		},
+		{
+			name: "fail - only symmetric algorithms",
+			document: func(url string) string {
+				return `{"issuer": "` + url + `/", "jwks_uri": "` + url + `/jwks.json", "id_token_signing_alg_values_supported": ["HS256", "none"]}`
+			},
+			expectedErrorMsg: "no supported signing algorithm",
+		},
+		{
+			name: "fail - invalid document",
+			document: func(url string) string {
+				return "Invalid Data"
+			},
+			expectedErrorMsg: "invalid OpenID Connect discovery document",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts := genDiscoveryTestServer(test.document)
+			defer ts.Close()
+
+			configuration, client, err := NewConfigurationFromDiscovery(context.Background(), ts.URL+test.issuerSuffix, defaultAudience, JWKClientOptions{})
+
+			if test.expectedErrorMsg != "" {
+				if err == nil {
+					t.Errorf("Discovery should have failed with error with substring: " + test.expectedErrorMsg)
+				} else if !strings.Contains(err.Error(), test.expectedErrorMsg) {
+					t.Errorf("Discovery should have failed with error with substring: " + test.expectedErrorMsg + ", but got: " + err.Error())
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, ts.URL+"/jwks.json", client.options.URI)
+			assert.Equal(t, ts.URL+"/", configuration.expectedClaims.Issuer)
+			assert.Equal(t, []jose.SignatureAlgorithm{jose.RS256}, configuration.algorithms)
+		})
+	}
+}
+
+func TestNewConfigurationFromDiscoveryNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	_, _, err := NewConfigurationFromDiscovery(context.Background(), ts.URL, defaultAudience, JWKClientOptions{})
+	assert.True(t, errors.Is(err, ErrInvalidDiscoveryDocument))
+	assert.Contains(t, err.Error(), "unexpected status code 404")
+}
+
+func TestNewConfigurationFromDiscoveryValidation(t *testing.T) {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	jsonWebKeyES384 := genECDSAJWK(jose.ES384, "keyES384")
+	ts := genDiscoveryTestServer(validDiscoveryDocument, jsonWebKeyRS256.Public(), jsonWebKeyES384.Public())
+	defer ts.Close()
+
+	configuration, _, err := NewConfigurationFromDiscovery(context.Background(), ts.URL, defaultAudience, JWKClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := NewValidator(configuration, nil)
+
+	token := getTestTokenWithKid(defaultAudience, ts.URL+"/", time.Now().Add(24*time.Hour), jose.RS256, jsonWebKeyRS256, "keyRS256")
+	assert.NoError(t, validator.ValidateToken(token))
+
+	token = getTestTokenWithKid(defaultAudience, ts.URL+"/", time.Now().Add(24*time.Hour), jose.ES384, jsonWebKeyES384, "keyES384")
+	assert.True(t, errors.Is(validator.ValidateToken(token), ErrInvalidAlgorithm))
+}
@@ -0,0 +1,129 @@
+package auth0
+
+import (
+	"errors"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// ValidationReason tells why a token was rejected.
+type ValidationReason int
+
+const (
+	// ReasonMalformed is used when the token cannot be parsed.
+	ReasonMalformed ValidationReason = iota + 1
+	// ReasonExpired is used when the token is expired.
+	ReasonExpired
+	// ReasonNotYetValid is used when the token is not valid yet.
+	ReasonNotYetValid
+	// ReasonIssuer is used when the issuer is not the expected one.
+	ReasonIssuer
+	// ReasonAudience is used when the audience is not the expected one.
+	ReasonAudience
+	// ReasonClaim is used when another claim, such as
+	// the subject or the ID, is not the expected one.
+	ReasonClaim
+	// ReasonSignature is used when the signature cannot be verified.
+	ReasonSignature
+	// ReasonAlgorithm is used when the signing algorithm is not allowed
+	// or does not match the alg, use or type of the key.
+	ReasonAlgorithm
+	// ReasonKeyNotFound is used when no key matches the token.
+	ReasonKeyNotFound
+	// ReasonKeyFetchFailed is used when the keys cannot be retrieved.
+	ReasonKeyFetchFailed
+)
+
+var reasonNames = map[ValidationReason]string{
+	ReasonMalformed:      "malformed token",
+	ReasonExpired:        "token expired",
+	ReasonNotYetValid:    "token not valid yet",
+	ReasonIssuer:         "invalid issuer",
+	ReasonAudience:       "invalid audience",
+	ReasonClaim:          "invalid claim",
+	ReasonSignature:      "invalid signature",
+	ReasonAlgorithm:      "invalid algorithm",
+	ReasonKeyNotFound:    "key not found",
+	ReasonKeyFetchFailed: "key fetch failed",
+}
+
+func (r ValidationReason) String() string {
+	if name, ok := reasonNames[r]; ok {
+		return name
+	}
+	return "invalid token"
+}
+
+// ValidationError is returned when a token is rejected.
+// It wraps the cause of the failure, which stays
+// reachable with errors.Is and errors.As.
+type ValidationError struct {
+	Reason ValidationReason
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Err == nil {
+		return e.Reason.String()
+	}
+	return e.Reason.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause of the failure.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a ValidationError without cause and with
+// the same reason, so that errors.Is(err, &ValidationError{Reason: ReasonExpired})
+// tells whether the token was rejected because it is expired.
+func (e *ValidationError) Is(target error) bool {
+	t, ok := target.(*ValidationError)
+	return ok && t.Err == nil && t.Reason == e.Reason
+}
+
+// newValidationError wraps err with reason, unless err
+// is already a ValidationError which is returned as is.
+func newValidationError(reason ValidationReason, err error) error {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return err
+	}
+	return &ValidationError{Reason: reason, Err: err}
+}
+
+// claimsError wraps an error returned by jwt.Claims.Validate.
+func claimsError(err error) error {
+	switch err {
+	case jwt.ErrExpired:
+		return newValidationError(ReasonExpired, err)
+	case jwt.ErrNotValidYet:
+		return newValidationError(ReasonNotYetValid, err)
+	case jwt.ErrInvalidIssuer:
+		return newValidationError(ReasonIssuer, err)
+	case jwt.ErrInvalidAudience:
+		return newValidationError(ReasonAudience, err)
+	default:
+		return newValidationError(ReasonClaim, err)
+	}
+}
+
+// signatureError wraps an error returned when verifying
+// the signature of a token and unmarshalling its claims.
+func signatureError(err error) error {
+	switch err {
+	case jose.ErrCryptoFailure, jose.ErrUnsupportedKeyType, jose.ErrUnsupportedAlgorithm:
+		return newValidationError(ReasonSignature, err)
+	default:
+		return newValidationError(ReasonMalformed, err)
+	}
+}
+
+// keyError wraps an error returned when retrieving the key of a token.
+func keyError(err error) error {
+	if errors.Is(err, ErrNoKeyFound) || errors.Is(err, ErrKeyExpired) {
+		return newValidationError(ReasonKeyNotFound, err)
+	}
+	return newValidationError(ReasonKeyFetchFailed, err)
+}
@@ -0,0 +1,148 @@
+package auth0
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestValidationErrorReasons(t *testing.T) {
+	hs256 := NewConfiguration(defaultSecretProvider, defaultAudience, defaultIssuer, jose.HS256)
+
+	notYetValid := defaultTestClaims()
+	notYetValid.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	testCases := []struct {
+		name          string
+		configuration Configuration
+		token         string
+		reason        ValidationReason
+		cause         error
+	}{
+		{
+			name:          "expired",
+			configuration: hs256,
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(-time.Hour), jose.HS256, defaultSecret),
+			reason:        ReasonExpired,
+			cause:         jwt.ErrExpired,
+		},
+		{
+			name:          "not yet valid",
+			configuration: hs256,
+			token:         getTestTokenWithClaims(jose.HS256, defaultSecret, notYetValid),
+			reason:        ReasonNotYetValid,
+			cause:         jwt.ErrNotValidYet,
+		},
+		{
+			name:          "issuer",
+			configuration: hs256,
+			token:         getTestToken(defaultAudience, "other issuer", time.Now().Add(time.Hour), jose.HS256, defaultSecret),
+			reason:        ReasonIssuer,
+			cause:         jwt.ErrInvalidIssuer,
+		},
+		{
+			name:          "audience",
+			configuration: hs256,
+			token:         getTestToken([]string{"other audience"}, defaultIssuer, time.Now().Add(time.Hour), jose.HS256, defaultSecret),
+			reason:        ReasonAudience,
+			cause:         jwt.ErrInvalidAudience,
+		},
+		{
+			name:          "signature",
+			configuration: hs256,
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(time.Hour), jose.HS256, []byte("other secret")),
+			reason:        ReasonSignature,
+			cause:         jose.ErrCryptoFailure,
+		},
+		{
+			name:          "algorithm",
+			configuration: hs256,
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(time.Hour), jose.HS384, defaultSecret),
+			reason:        ReasonAlgorithm,
+			cause:         ErrInvalidAlgorithm,
+		},
+		{
+			name:          "key not found",
+			configuration: NewConfiguration(SecretProviderFunc(func(_ *jwt.JSONWebToken) (interface{}, error) { return nil, ErrNoKeyFound }), defaultAudience, defaultIssuer, jose.HS256),
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(time.Hour), jose.HS256, defaultSecret),
+			reason:        ReasonKeyNotFound,
+			cause:         ErrNoKeyFound,
+		},
+		{
+			name:          "key fetch failed",
+			configuration: NewConfiguration(SecretProviderFunc(func(_ *jwt.JSONWebToken) (interface{}, error) { return nil, ErrInvalidContentType }), defaultAudience, defaultIssuer, jose.HS256),
+			token:         getTestToken(defaultAudience, defaultIssuer, time.Now().Add(time.Hour), jose.HS256, defaultSecret),
+			reason:        ReasonKeyFetchFailed,
+			cause:         ErrInvalidContentType,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			validator, req := genTestConfiguration(test.configuration, test.token)
+			_, err := validator.ValidateRequest(req)
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validation should have failed with a ValidationError, but got: %v", err)
+			}
+			assert.Equal(t, test.reason, validationErr.Reason)
+			assert.True(t, errors.Is(err, &ValidationError{Reason: test.reason}))
+			assert.True(t, errors.Is(err, test.cause))
+		})
+	}
+}
+
+func TestValidationErrorMalformed(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://localhost", nil)
+	req.Header.Set("Authorization", "Bearer broken")
+
+	for _, extractor := range []RequestTokenExtractorFunc{FromHeader, FromParams, FromCookie} {
+		r := httptest.NewRequest("GET", "http://localhost?token=broken", nil)
+		r.Header.Set("Authorization", "Bearer broken")
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: "broken"})
+
+		_, err := extractor(r)
+		assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonMalformed}), "got: %v", err)
+	}
+
+	validator := NewValidator(NewConfiguration(defaultSecretProvider, defaultAudience, defaultIssuer, jose.HS256), nil)
+	_, err := validator.ValidateRequest(req)
+	assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonMalformed}), "got: %v", err)
+	assert.False(t, errors.Is(err, &ValidationError{Reason: ReasonSignature}))
+}
+
+func TestValidationErrorFromJWKClient(t *testing.T) {
+	opts, tokenRS256, _, err := genNewTestServer(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewJWKClient(opts, nil)
+
+	_, err = client.GetSecret(tokenRS256)
+	assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonKeyNotFound}), "got: %v", err)
+	assert.True(t, errors.Is(err, ErrNoKeyFound))
+
+	client = NewJWKClient(JWKClientOptions{URI: "http://localhost:0/jwks"}, nil)
+	_, err = client.GetSecret(tokenRS256)
+	assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonKeyFetchFailed}), "got: %v", err)
+
+	validator := NewValidator(NewConfiguration(client, defaultAudience, defaultIssuer, jose.RS256), nil)
+	err = validator.ValidateToken(tokenRS256)
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, ReasonKeyFetchFailed, validationErr.Reason, "the reason given by the client should be kept")
+	assert.False(t, errors.As(validationErr.Err, new(*ValidationError)), "the error should not be wrapped twice")
+}
+
+func TestValidationReasonString(t *testing.T) {
+	assert.Equal(t, "token expired", ReasonExpired.String())
+	assert.Equal(t, "invalid token", ValidationReason(0).String())
+	assert.Equal(t, "token expired: cause", (&ValidationError{Reason: ReasonExpired, Err: errors.New("cause")}).Error())
+}
@@ -125,10 +125,6 @@ func Auth0Groups(wantedGroups ...string) gin.HandlerFunc {
 
 func shouldAccess(wantedGroups []string, groups []interface{}) bool {
 
-	if len(groups) < 1 {
-		return true
-	}
-
 	for _, wantedScope := range wantedGroups {
 
 		scopeFound := false
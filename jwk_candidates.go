@@ -0,0 +1,137 @@
+package auth0
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// DefaultMaxKeyCandidates is the maximum number of keys tried for a token
+// without key ID when MaxKeyCandidates is not configured.
+var DefaultMaxKeyCandidates = 5
+
+// keyForToken returns the key verifying the signature of a token without
+// key ID. The candidates are the last downloaded keys compatible with the
+// alg header and, when present, the x5t or x5t#S256 thumbprint headers.
+// The keys are downloaded again when none of them is compatible, or once
+// more when none of them verifies the token, as it may be signed with a
+// key rotated since, within the limit of MinDownloadInterval.
+//
+// The signature is verified to find the key, and verified again by the
+// validator with the returned key: unlike tokens with a key ID, tokens
+// without one cost two verifications.
+func (j *JWKClient) keyForToken(ctx context.Context, token *jwt.JSONWebToken) (jose.JSONWebKey, error) {
+	header := token.Headers[0]
+
+	set, _ := j.lastKeySet()
+	candidates := j.keyCandidates(set.Keys, header)
+	downloaded := false
+	if len(candidates) < 1 {
+		keys, _, err := j.sharedDownloadKeys(ctx)
+		if err != nil {
+			return jose.JSONWebKey{}, keyError(err)
+		}
+		candidates = j.keyCandidates(keys, header)
+		downloaded = true
+	}
+	if key, ok := verifyingKey(token, candidates, nil); ok {
+		return key, nil
+	}
+	if downloaded {
+		return jose.JSONWebKey{}, keyError(ErrNoKeyFound)
+	}
+
+	keys, _, err := j.sharedDownloadKeys(ctx)
+	if err != nil {
+		return jose.JSONWebKey{}, keyError(err)
+	}
+	if key, ok := verifyingKey(token, j.keyCandidates(keys, header), candidates); ok {
+		return key, nil
+	}
+	return jose.JSONWebKey{}, keyError(ErrNoKeyFound)
+}
+
+// verifyingKey returns the first candidate verifying the
+// signature of the token, skipping the keys already tried.
+func verifyingKey(token *jwt.JSONWebToken, candidates, tried []jose.JSONWebKey) (jose.JSONWebKey, bool) {
+	for _, key := range candidates {
+		if containsKey(tried, key) {
+			continue
+		}
+		if err := token.Claims(key); err == nil {
+			return key, true
+		}
+	}
+	return jose.JSONWebKey{}, false
+}
+
+// containsKey reports whether keys holds a key with the same thumbprint as key.
+func containsKey(keys []jose.JSONWebKey, key jose.JSONWebKey) bool {
+	if len(keys) < 1 {
+		return false
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return false
+	}
+	for _, other := range keys {
+		if otherThumbprint, err := other.Thumbprint(crypto.SHA256); err == nil && bytes.Equal(thumbprint, otherThumbprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyCandidates returns at most MaxKeyCandidates keys compatible with the header.
+func (j *JWKClient) keyCandidates(keys []jose.JSONWebKey, header jose.Header) []jose.JSONWebKey {
+	var candidates []jose.JSONWebKey
+	for _, key := range keys {
+		if len(candidates) >= j.options.MaxKeyCandidates {
+			break
+		}
+		if checkKeyAlgorithm(key, header.Algorithm) == nil && keyMatchesThumbprints(key, header) {
+			candidates = append(candidates, key)
+		}
+	}
+	return candidates
+}
+
+// keyMatchesThumbprints reports whether the first certificate of the key
+// has the thumbprints of the x5t and x5t#S256 headers, if present.
+func keyMatchesThumbprints(key jose.JSONWebKey, header jose.Header) bool {
+	sha1Thumbprint, _ := header.ExtraHeaders["x5t"].(string)
+	sha256Thumbprint, _ := header.ExtraHeaders["x5t#S256"].(string)
+	if sha1Thumbprint == "" && sha256Thumbprint == "" {
+		return true
+	}
+	if len(key.Certificates) < 1 {
+		return false
+	}
+
+	raw := key.Certificates[0].Raw
+	if sha1Thumbprint != "" {
+		sum := sha1.Sum(raw)
+		if !thumbprintEqual(sha1Thumbprint, sum[:]) {
+			return false
+		}
+	}
+	if sha256Thumbprint != "" {
+		sum := sha256.Sum256(raw)
+		if !thumbprintEqual(sha256Thumbprint, sum[:]) {
+			return false
+		}
+	}
+	return true
+}
+
+// thumbprintEqual compares a base64url encoded thumbprint, padded or not, to sum.
+func thumbprintEqual(thumbprint string, sum []byte) bool {
+	return strings.TrimRight(thumbprint, "=") == base64.RawURLEncoding.EncodeToString(sum)
+}
@@ -0,0 +1,208 @@
+package auth0
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func genTestCertificate(t *testing.T, key *rsa.PrivateKey) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "go-auth0"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func getTestTokenWithHeaders(t *testing.T, alg jose.SignatureAlgorithm, key interface{}, headers map[jose.HeaderKey]interface{}) *jwt.JSONWebToken {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, &jose.SignerOptions{ExtraHeaders: headers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Issuer:   defaultIssuer,
+		Audience: defaultAudience,
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestGetSecretWithoutKeyID(t *testing.T) {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	jsonWebKeyES384 := genECDSAJWK(jose.ES384, "keyES384")
+	otherRS256 := genRSASSAJWK(jose.RS256, "otherRS256")
+	ts, counter := genJWKSTestServer(t, nil, otherRS256.Public(), jsonWebKeyES384.Public(), jsonWebKeyRS256.Public())
+	defer ts.Close()
+
+	tests := []struct {
+		name           string
+		options        JWKClientOptions
+		token          *jwt.JSONWebToken
+		expectedReason ValidationReason
+	}{
+		{
+			name:           "disabled",
+			options:        JWKClientOptions{URI: ts.URL},
+			token:          getTestTokenWithHeaders(t, jose.RS256, jsonWebKeyRS256.Key, nil),
+			expectedReason: ReasonKeyNotFound,
+		},
+		{
+			name:    "RS256",
+			options: JWKClientOptions{URI: ts.URL, AllowMissingKeyID: true},
+			token:   getTestTokenWithHeaders(t, jose.RS256, jsonWebKeyRS256.Key, nil),
+		},
+		{
+			name:    "ES384",
+			options: JWKClientOptions{URI: ts.URL, AllowMissingKeyID: true},
+			token:   getTestTokenWithHeaders(t, jose.ES384, jsonWebKeyES384.Key, nil),
+		},
+		{
+			name:           "unknown key",
+			options:        JWKClientOptions{URI: ts.URL, AllowMissingKeyID: true},
+			token:          getTestTokenWithHeaders(t, jose.RS256, genRSASSAJWK(jose.RS256, "").Key, nil),
+			expectedReason: ReasonKeyNotFound,
+		},
+		{
+			name:           "no compatible key",
+			options:        JWKClientOptions{URI: ts.URL, AllowMissingKeyID: true},
+			token:          getTestTokenWithHeaders(t, jose.ES256, genECDSAJWK(jose.ES256, "").Key, nil),
+			expectedReason: ReasonKeyNotFound,
+		},
+		{
+			name:           "too many candidates",
+			options:        JWKClientOptions{URI: ts.URL, AllowMissingKeyID: true, MaxKeyCandidates: 1},
+			token:          getTestTokenWithHeaders(t, jose.RS256, jsonWebKeyRS256.Key, nil),
+			expectedReason: ReasonKeyNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewJWKClient(test.options, nil)
+			secret, err := client.GetSecret(test.token)
+			if test.expectedReason != 0 {
+				assert.True(t, errors.Is(err, &ValidationError{Reason: test.expectedReason}), "got: %v", err)
+				return
+			}
+			assert.NoError(t, err)
+
+			validator := NewValidator(NewConfigurationTrustProvider(SecretProviderFunc(func(*jwt.JSONWebToken) (interface{}, error) {
+				return secret, nil
+			}), defaultAudience, defaultIssuer), nil)
+			assert.NoError(t, validator.ValidateToken(test.token))
+		})
+	}
+
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL, AllowMissingKeyID: true}, nil)
+	atomic.StoreUint64(counter, 0)
+	for i := 0; i < 3; i++ {
+		_, err := client.GetSecret(getTestTokenWithHeaders(t, jose.RS256, genRSASSAJWK(jose.RS256, "").Key, nil))
+		assert.Error(t, err)
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter), "keys should not be downloaded again within MinDownloadInterval")
+}
+
+func TestGetSecretWithoutKeyIDRotation(t *testing.T) {
+	oldKey := genRSASSAJWK(jose.RS256, "old")
+	newKey := genRSASSAJWK(jose.RS256, "new")
+	var rotated int32
+	ts, counter := genJWKSTestServer(t, func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+		if atomic.LoadInt32(&rotated) == 0 {
+			return false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(JWKS{Keys: []jose.JSONWebKey{newKey.Public(), oldKey.Public()}})
+		return true
+	}, oldKey.Public())
+	defer ts.Close()
+
+	clock := newFakeClock()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL, AllowMissingKeyID: true, MinDownloadInterval: time.Minute, Clock: clock}, nil)
+	key, err := client.GetSecret(getTestTokenWithHeaders(t, jose.RS256, oldKey.Key, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "old", key.(jose.JSONWebKey).KeyID)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+
+	atomic.StoreInt32(&rotated, 1)
+	newToken := getTestTokenWithHeaders(t, jose.RS256, newKey.Key, nil)
+	_, err = client.GetSecret(newToken)
+	assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonKeyNotFound}), "got: %v", err)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter), "the keys should not be downloaded again within MinDownloadInterval")
+
+	clock.Advance(2 * time.Minute)
+	key, err = client.GetSecret(newToken)
+	assert.NoError(t, err, "the keys should be downloaded again when no candidate verifies the token")
+	assert.Equal(t, "new", key.(jose.JSONWebKey).KeyID)
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+
+	key, err = client.GetSecret(newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", key.(jose.JSONWebKey).KeyID)
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+}
+
+func TestGetSecretWithoutKeyIDThumbprints(t *testing.T) {
+	first := genRSASSAJWK(jose.RS256, "first")
+	first.Certificates = []*x509.Certificate{genTestCertificate(t, first.Key.(*rsa.PrivateKey))}
+	second := genRSASSAJWK(jose.RS256, "second")
+	second.Certificates = []*x509.Certificate{genTestCertificate(t, second.Key.(*rsa.PrivateKey))}
+	ts, _ := genJWKSTestServer(t, nil, first.Public(), second.Public())
+	defer ts.Close()
+
+	sha1Sum := sha1.Sum(second.Certificates[0].Raw)
+	sha256Sum := sha256.Sum256(second.Certificates[0].Raw)
+	x5t := base64.RawURLEncoding.EncodeToString(sha1Sum[:])
+	x5tS256 := base64.RawURLEncoding.EncodeToString(sha256Sum[:])
+
+	tests := []struct {
+		name          string
+		headers       map[jose.HeaderKey]interface{}
+		expectedError bool
+	}{
+		{name: "x5t", headers: map[jose.HeaderKey]interface{}{"x5t": x5t}},
+		{name: "padded x5t", headers: map[jose.HeaderKey]interface{}{"x5t": x5t + "="}},
+		{name: "x5t#S256", headers: map[jose.HeaderKey]interface{}{"x5t#S256": x5tS256}},
+		{name: "both", headers: map[jose.HeaderKey]interface{}{"x5t": x5t, "x5t#S256": x5tS256}},
+		{name: "mismatch", headers: map[jose.HeaderKey]interface{}{"x5t": x5t, "x5t#S256": "unknown"}, expectedError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := getTestTokenWithHeaders(t, jose.RS256, second.Key, test.headers)
+			client := NewJWKClient(JWKClientOptions{URI: ts.URL, AllowMissingKeyID: true, MaxKeyCandidates: 1}, nil)
+
+			_, err := client.GetSecret(token)
+			assert.Equal(t, test.expectedError, err != nil, "got: %v", err)
+		})
+	}
+}
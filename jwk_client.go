@@ -2,12 +2,17 @@ package auth0
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"gopkg.in/square/go-jose.v2/jwt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/square/go-jose.v2"
 )
@@ -15,11 +20,89 @@ import (
 var (
 	ErrInvalidContentType = errors.New("should have a JSON content type for JWKS endpoint")
 	ErrInvalidAlgorithm   = errors.New("algorithm is invalid")
+	// ErrResponseTooLarge is returned when the JWKS
+	// response is larger than MaxResponseSize.
+	ErrResponseTooLarge = errors.New("JWKS response is too large")
+
+	// DefaultMaxResponseSize is the maximum size of
+	// the JWKS response when none is configured.
+	DefaultMaxResponseSize int64 = 1 << 20
 )
 
+// maxErrorBodySize bounds the part of the body of
+// an unexpected response kept by a ResponseStatusError.
+const maxErrorBodySize = 256
+
+// ResponseStatusError is returned when the JWKS
+// endpoint answers with a non-2xx status.
+type ResponseStatusError struct {
+	StatusCode int
+	// Body is the beginning of the body of the response.
+	Body string
+}
+
+func (e *ResponseStatusError) Error() string {
+	return fmt.Sprintf("unexpected JWKS response status %d: %s", e.StatusCode, e.Body)
+}
+
 type JWKClientOptions struct {
 	URI    string
 	Client *http.Client
+	// RefreshInterval is the delay between two background refreshes
+	// of the keys started with Start, used when the JWKS response has
+	// no Cache-Control max-age directive. Defaults to DefaultRefreshInterval.
+	RefreshInterval time.Duration
+	// MinRefreshInterval is the minimum delay between two background
+	// refreshes, whatever the max-age of the JWKS response.
+	// It is also the delay before retrying a failed refresh.
+	// Defaults to DefaultMinRefreshInterval.
+	MinRefreshInterval time.Duration
+	// MinDownloadInterval is the minimum delay between two downloads
+	// of the keys triggered by cache misses. Misses happening sooner
+	// are answered from the last downloaded keys.
+	// Defaults to DefaultMinDownloadInterval, a negative value disables the limit.
+	MinDownloadInterval time.Duration
+	// UnknownKeyTTL is how long a key ID missing from the downloaded keys
+	// is remembered, so that tokens carrying it are rejected with
+	// ErrNoKeyFound without downloading the keys again.
+	// Defaults to DefaultUnknownKeyTTL, a negative value disables it.
+	UnknownKeyTTL time.Duration
+	// MaxResponseSize is the maximum size in bytes of the JWKS response.
+	// Defaults to DefaultMaxResponseSize.
+	MaxResponseSize int64
+	// MaxRetries is the number of times a download failing because of
+	// a network error or a 429 or 5xx status is retried. Zero disables retries.
+	MaxRetries int
+	// RetryBaseDelay is the delay before the first retry, doubled
+	// for each retry and randomized. Defaults to DefaultRetryBaseDelay.
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the delay between two retries.
+	// Defaults to DefaultRetryMaxDelay.
+	RetryMaxDelay time.Duration
+	// BreakerThreshold is the number of consecutive failed downloads
+	// opening the circuit breaker. While open, the keys are not
+	// downloaded and the last downloaded keys are served, even if
+	// expired from the cache. Zero disables the circuit breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the circuit breaker stays open before
+	// letting a download through. Defaults to DefaultBreakerCooldown.
+	BreakerCooldown time.Duration
+	// OnStaleKey is called with the key ID each time an expired key is
+	// served because the keys cannot be downloaded, either from the grace
+	// period of a StaleKeyCacher or while the circuit breaker is open.
+	OnStaleKey func(keyID string)
+	// AllowMissingKeyID lets GetSecret find the key of a token without
+	// kid header among the last downloaded keys compatible with its alg,
+	// x5t and x5t#S256 headers, trying each of them to verify the token.
+	// The validator then verifies these tokens a second time.
+	AllowMissingKeyID bool
+	// MaxKeyCandidates is the maximum number of keys tried for a token
+	// without kid header. Defaults to DefaultMaxKeyCandidates.
+	MaxKeyCandidates int
+	// Clock tells the time used by the download limits and the circuit
+	// breaker, and the time downloaded key sets are stamped with.
+	// Defaults to the system clock.
+	Clock Clock
 }
 
 type JWKS struct {
@@ -31,6 +114,9 @@ type JWKClient struct {
 	mu        sync.Mutex
 	options   JWKClientOptions
 	extractor RequestTokenExtractor
+	refresher refresher
+	downloads downloads
+	breaker   breaker
 }
 
 // NewJWKClient creates a new JWKClient instance from the
@@ -52,6 +138,36 @@ func NewJWKClientWithCache(options JWKClientOptions, extractor RequestTokenExtra
 	if options.Client == nil {
 		options.Client = http.DefaultClient
 	}
+	if options.RefreshInterval <= 0 {
+		options.RefreshInterval = DefaultRefreshInterval
+	}
+	if options.MinRefreshInterval <= 0 {
+		options.MinRefreshInterval = DefaultMinRefreshInterval
+	}
+	if options.MinDownloadInterval == 0 {
+		options.MinDownloadInterval = DefaultMinDownloadInterval
+	}
+	if options.UnknownKeyTTL == 0 {
+		options.UnknownKeyTTL = DefaultUnknownKeyTTL
+	}
+	if options.MaxResponseSize <= 0 {
+		options.MaxResponseSize = DefaultMaxResponseSize
+	}
+	if options.RetryBaseDelay <= 0 {
+		options.RetryBaseDelay = DefaultRetryBaseDelay
+	}
+	if options.RetryMaxDelay <= 0 {
+		options.RetryMaxDelay = DefaultRetryMaxDelay
+	}
+	if options.BreakerCooldown <= 0 {
+		options.BreakerCooldown = DefaultBreakerCooldown
+	}
+	if options.MaxKeyCandidates <= 0 {
+		options.MaxKeyCandidates = DefaultMaxKeyCandidates
+	}
+	if options.Clock == nil {
+		options.Clock = systemClock{}
+	}
 
 	return &JWKClient{
 		keyCacher: keyCacher,
@@ -62,19 +178,48 @@ func NewJWKClientWithCache(options JWKClientOptions, extractor RequestTokenExtra
 
 // GetKey returns the key associated with the provided ID.
 func (j *JWKClient) GetKey(ID string) (jose.JSONWebKey, error) {
+	return j.GetKeyContext(context.Background(), ID)
+}
+
+// GetKeyContext returns the key associated with the provided ID.
+// Downloading the keys is aborted as soon as ctx is done.
+func (j *JWKClient) GetKeyContext(ctx context.Context, ID string) (jose.JSONWebKey, error) {
 	searchedKey, err := j.keyCacher.Get(ID)
 
 	if err != nil {
-		j.mu.Lock()
-		defer j.mu.Unlock()
+		if j.isUnknownKey(ID) {
+			return jose.JSONWebKey{}, keyError(ErrNoKeyFound)
+		}
 
-		keys, err := j.downloadKeys()
+		keys, fresh, err := j.sharedDownloadKeys(ctx)
 		if err != nil {
-			return jose.JSONWebKey{}, err
+			if key, ok := j.gracedKey(ID); ok {
+				return key, nil
+			}
+			if key, ok := j.staleKey(ID); ok {
+				return key, nil
+			}
+			return jose.JSONWebKey{}, keyError(err)
 		}
+		if !fresh {
+			// keys downloaded before the miss are not added to the cache
+			// again, which would restart their age
+			for _, key := range keys {
+				if key.KeyID == ID {
+					return key, nil
+				}
+			}
+			return jose.JSONWebKey{}, keyError(ErrNoKeyFound)
+		}
+		j.mu.Lock()
+		defer j.mu.Unlock()
+
 		addedKey, err := j.keyCacher.Add(ID, keys)
 		if err != nil {
-			return jose.JSONWebKey{}, err
+			if err == ErrNoKeyFound {
+				j.addUnknownKey(ID)
+			}
+			return jose.JSONWebKey{}, keyError(err)
 		}
 		return *addedKey, nil
 	}
@@ -82,44 +227,169 @@ func (j *JWKClient) GetKey(ID string) (jose.JSONWebKey, error) {
 	return *searchedKey, nil
 }
 
-func (j *JWKClient) downloadKeys() ([]jose.JSONWebKey, error) {
-	req, err := http.NewRequest("GET", j.options.URI, new(bytes.Buffer))
+// gracedKey returns the expired key kept by the cacher
+// for a grace period, if it is a StaleKeyCacher.
+func (j *JWKClient) gracedKey(keyID string) (jose.JSONWebKey, bool) {
+	staleCacher, ok := j.keyCacher.(StaleKeyCacher)
+	if !ok {
+		return jose.JSONWebKey{}, false
+	}
+	key, err := staleCacher.GetStale(keyID)
+	if err != nil {
+		return jose.JSONWebKey{}, false
+	}
+	j.reportStaleKey(keyID)
+	return *key, true
+}
+
+// reportStaleKey calls OnStaleKey, if configured.
+func (j *JWKClient) reportStaleKey(keyID string) {
+	if j.options.OnStaleKey != nil {
+		j.options.OnStaleKey(keyID)
+	}
+}
+
+func (j *JWKClient) downloadKeys(ctx context.Context) ([]jose.JSONWebKey, error) {
+	jwks, err := j.fetchKeys(ctx)
 	if err != nil {
 		return []jose.JSONWebKey{}, err
 	}
+	return jwks.keys, nil
+}
+
+// jwksResponse holds the keys of a JWKS response
+// along with its caching directives and validators.
+type jwksResponse struct {
+	keys         []jose.JSONWebKey
+	maxAge       time.Duration
+	etag         string
+	lastModified string
+}
+
+// requestKeys downloads the keys once. When keys were downloaded before,
+// the request is conditional and a 304 Not Modified response returns
+// them again, along with the validators of the response if it has any.
+func (j *JWKClient) requestKeys(ctx context.Context) (jwksResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", j.options.URI, new(bytes.Buffer))
+	if err != nil {
+		return jwksResponse{}, err
+	}
+
+	last, conditional := j.lastKeySet()
+	if conditional {
+		if last.ETag != "" {
+			req.Header.Set("If-None-Match", last.ETag)
+		}
+		if last.LastModified != "" {
+			req.Header.Set("If-Modified-Since", last.LastModified)
+		}
+	}
 	resp, err := j.options.Client.Do(req)
 
 	if err != nil {
-		return []jose.JSONWebKey{}, err
+		return jwksResponse{}, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		jwks := jwksResponse{
+			keys:         last.Keys,
+			maxAge:       parseMaxAge(resp.Header.Get("Cache-Control")),
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		}
+		if jwks.etag == "" {
+			jwks.etag = last.ETag
+		}
+		if jwks.lastModified == "" {
+			jwks.lastModified = last.LastModified
+		}
+		return jwks, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return jwksResponse{}, &ResponseStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+
 	if contentH := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentH, "application/json") &&
 		!strings.HasPrefix(contentH, "application/jwk-set+json") {
-		return []jose.JSONWebKey{}, ErrInvalidContentType
+		return jwksResponse{}, ErrInvalidContentType
 	}
 
-	var jwks = JWKS{}
-	err = json.NewDecoder(resp.Body).Decode(&jwks)
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, j.options.MaxResponseSize+1))
+	if err != nil {
+		return jwksResponse{}, err
+	}
+	if int64(len(body)) > j.options.MaxResponseSize {
+		return jwksResponse{}, ErrResponseTooLarge
+	}
 
+	keys, err := decodeSigningKeys(body)
 	if err != nil {
-		return []jose.JSONWebKey{}, err
+		return jwksResponse{}, err
 	}
 
-	if len(jwks.Keys) < 1 {
-		return []jose.JSONWebKey{}, ErrNoKeyFound
+	if len(keys) < 1 {
+		return jwksResponse{}, ErrNoKeyFound
 	}
 
-	return jwks.Keys, nil
+	return jwksResponse{
+		keys:         keys,
+		maxAge:       parseMaxAge(resp.Header.Get("Cache-Control")),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// decodeSigningKeys decodes the keys of a JWKS, skipping the keys
+// without key type, the keys not meant to verify signatures and the
+// keys which cannot be decoded, such as keys of unsupported types.
+func decodeSigningKeys(data []byte) ([]jose.JSONWebKey, error) {
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make([]jose.JSONWebKey, 0, len(jwks.Keys))
+	for _, raw := range jwks.Keys {
+		var params struct {
+			KeyType string `json:"kty"`
+			Use     string `json:"use"`
+		}
+		if err := json.Unmarshal(raw, &params); err != nil || params.KeyType == "" {
+			continue
+		}
+		if params.Use != "" && params.Use != "sig" {
+			continue
+		}
+
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(raw); err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
 }
 
 // GetSecret implements the GetSecret method of the SecretProvider interface.
 func (j *JWKClient) GetSecret(token *jwt.JSONWebToken) (interface{}, error) {
+	return j.GetSecretContext(context.Background(), token)
+}
+
+// GetSecretContext implements the GetSecretContext method of the SecretProviderContext interface.
+func (j *JWKClient) GetSecretContext(ctx context.Context, token *jwt.JSONWebToken) (interface{}, error) {
 	if len(token.Headers) < 1 {
-		return nil, ErrNoJWTHeaders
+		return nil, newValidationError(ReasonMalformed, ErrNoJWTHeaders)
 	}
 
 	header := token.Headers[0]
+	if header.KeyID == "" && j.options.AllowMissingKeyID {
+		return j.keyForToken(ctx, token)
+	}
 
-	return j.GetKey(header.KeyID)
+	return j.GetKeyContext(ctx, header.KeyID)
 }
@@ -1,11 +1,14 @@
 package auth0
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"gopkg.in/square/go-jose.v2/jwt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -55,7 +58,7 @@ func TestJWKDownloadKeySuccess(t *testing.T) {
 	}
 	client := NewJWKClient(opts, nil)
 
-	keys, err := client.downloadKeys()
+	keys, err := client.downloadKeys(context.Background())
 	if err != nil || len(keys) < 1 {
 		t.Errorf("The keys should have been correctly received: %v", err)
 		t.FailNow()
@@ -73,7 +76,7 @@ func TestJWKDownloadKeyBadRequest(t *testing.T) {
 	opts := JWKClientOptions{URI: "\t.://"}
 	client := NewJWKClient(opts, nil)
 
-	keys, err := client.downloadKeys()
+	keys, err := client.downloadKeys(context.Background())
 	assert.Error(t, err)
 	assert.Empty(t, keys)
 }
@@ -82,7 +85,7 @@ func TestJWKDownloadKeyFailed(t *testing.T) {
 	opts := JWKClientOptions{URI: "invalidURI"}
 	client := NewJWKClient(opts, nil)
 
-	keys, err := client.downloadKeys()
+	keys, err := client.downloadKeys(context.Background())
 	assert.Error(t, err)
 	assert.Empty(t, keys)
 }
@@ -121,7 +124,7 @@ func TestJWKDownloadKeyInvalid(t *testing.T) {
 	opts := JWKClientOptions{URI: ts.URL}
 	client := NewJWKClient(opts, nil)
 
-	_, err := client.downloadKeys()
+	_, err := client.downloadKeys(context.Background())
 	if err != ErrInvalidContentType {
 		t.Errorf("An ErrInvalidContentType should be returned in case of invalid Content-Type Header.")
 	}
@@ -135,7 +138,7 @@ func TestJWKDownloadKeyInvalid(t *testing.T) {
 	opts = JWKClientOptions{URI: ts.URL}
 	client = NewJWKClient(opts, nil)
 
-	_, err = client.downloadKeys()
+	_, err = client.downloadKeys(context.Background())
 	if err == nil {
 		t.Errorf("An non JSON payload should return an error.")
 	}
@@ -279,3 +282,311 @@ func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	atomic.AddUint64(m.ops, 1)
 	return m.rt.RoundTrip(req)
 }
+
+func TestGetKeyContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		_, err := client.GetKeyContext(ctx, "key1")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetKeyContext should have returned once the context was done")
+	}
+}
+
+func TestGetSecretContext(t *testing.T) {
+	opts, tokenRS256, _, err := genNewTestServer(true)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	client := NewJWKClient(opts, nil)
+
+	key, err := client.GetSecretContext(context.Background(), tokenRS256)
+	assert.NoError(t, err)
+	assert.NotNil(t, key)
+}
+
+// notModifiedHandler sets the validators of the keys, answering and counting
+// 304 Not Modified to the requests carrying matching validators.
+func notModifiedHandler(etag, lastModified string, notModified *uint64) jwksTestHandler {
+	return func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+		if (etag != "" && r.Header.Get("If-None-Match") == etag) ||
+			(etag == "" && lastModified != "" && r.Header.Get("If-Modified-Since") == lastModified) {
+			atomic.AddUint64(notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		if lastModified != "" {
+			w.Header().Set("Last-Modified", lastModified)
+		}
+		return false
+	}
+}
+
+func TestConditionalDownload(t *testing.T) {
+	tests := []struct {
+		name         string
+		etag         string
+		lastModified string
+	}{
+		{name: "etag", etag: `"v1"`},
+		{name: "last modified", lastModified: "Mon, 02 Jan 2006 15:04:05 GMT"},
+		{name: "both", etag: `"v1"`, lastModified: "Mon, 02 Jan 2006 15:04:05 GMT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+			var notModified uint64
+			ts, counter := genJWKSTestServer(t, notModifiedHandler(test.etag, test.lastModified, &notModified), jsonWebKeyRS256.Public())
+			defer ts.Close()
+			clock := newFakeClock()
+			client := NewJWKClient(JWKClientOptions{URI: ts.URL, Clock: clock}, nil)
+
+			_, err := client.GetKey("keyRS256")
+			assert.NoError(t, err)
+
+			// a later miss downloads the keys again
+			clock.Advance(DefaultMinDownloadInterval)
+			_, err = client.GetKey("unknown")
+			assert.True(t, errors.Is(err, ErrNoKeyFound))
+			assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+			assert.Equal(t, uint64(1), atomic.LoadUint64(&notModified))
+
+			keys, err := client.downloadKeys(context.Background())
+			assert.NoError(t, err)
+			assert.Len(t, keys, 1, "the last keys should be returned when not modified")
+			assert.Equal(t, uint64(2), atomic.LoadUint64(&notModified))
+		})
+	}
+}
+
+func TestConditionalDownloadWithoutValidators(t *testing.T) {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	var notModified uint64
+	ts, counter := genJWKSTestServer(t, notModifiedHandler("", "", &notModified), jsonWebKeyRS256.Public())
+	defer ts.Close()
+	clock := newFakeClock()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL, Clock: clock}, nil)
+
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	clock.Advance(DefaultMinDownloadInterval)
+	_, err = client.GetKey("unknown")
+	assert.True(t, errors.Is(err, ErrNoKeyFound))
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&notModified))
+}
+
+func TestConditionalRefreshExtendsKeyAge(t *testing.T) {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	var notModified uint64
+	ts, counter := genJWKSTestServer(t, notModifiedHandler(`"v1"`, "", &notModified), jsonWebKeyRS256.Public())
+	defer ts.Close()
+	clock := newFakeClock()
+	keyCacher := NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{MaxKeyAge: time.Minute, MaxCacheSize: MaxCacheSizeNoCheck, Clock: clock})
+	client := NewJWKClientWithCache(JWKClientOptions{URI: ts.URL, Clock: clock}, nil, keyCacher)
+
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	clock.Advance(2 * time.Minute)
+	_, err = client.keyCacher.Get("keyRS256")
+	assert.Equal(t, ErrKeyExpired, err)
+
+	client.refresh(context.Background())
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&notModified))
+	_, err = client.keyCacher.Get("keyRS256")
+	assert.NoError(t, err, "a not modified refresh should add the keys again")
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+}
+
+func TestConditionalDownloadFromKeySetCacher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-auth0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	var notModified uint64
+	ts, counter := genJWKSTestServer(t, notModifiedHandler(`"v1"`, "", &notModified), jsonWebKeyRS256.Public())
+	defer ts.Close()
+	clock := newFakeClock()
+	options := FileKeyCacherOptions{Dir: dir, MaxKeyAge: time.Minute, Clock: clock}
+
+	client := NewJWKClientWithCache(JWKClientOptions{URI: ts.URL, Clock: clock}, nil, NewFileKeyCacher(options))
+	_, err = client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	clock.Advance(2 * time.Minute)
+
+	// another process reusing the expired set of the file
+	keyCacher := NewFileKeyCacher(options)
+	client = NewJWKClientWithCache(JWKClientOptions{URI: ts.URL, Clock: clock}, nil, keyCacher)
+	_, err = client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&notModified))
+
+	set, ok := keyCacher.LastKeySet()
+	assert.True(t, ok)
+	assert.Equal(t, `"v1"`, set.ETag)
+	assert.Equal(t, clock.Now(), set.FetchedAt, "the age of the set should be extended")
+}
+
+func TestJWKDownloadKeyStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		expectedBody string
+	}{
+		{name: "server error", status: http.StatusInternalServerError, body: `{"error": "internal"}`, expectedBody: `{"error": "internal"}`},
+		{name: "not found", status: http.StatusNotFound, body: "", expectedBody: ""},
+		{name: "long body", status: http.StatusBadGateway, body: strings.Repeat("a", 10*maxErrorBodySize), expectedBody: strings.Repeat("a", maxErrorBodySize)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(test.status)
+				fmt.Fprint(w, test.body)
+			}))
+			defer ts.Close()
+			client := NewJWKClient(JWKClientOptions{URI: ts.URL}, nil)
+
+			_, err := client.downloadKeys(context.Background())
+			var statusErr *ResponseStatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("A ResponseStatusError should be returned, but got: %v", err)
+			}
+			assert.Equal(t, test.status, statusErr.StatusCode)
+			assert.Equal(t, test.expectedBody, statusErr.Body)
+
+			_, err = client.GetKey("keyRS256")
+			assert.True(t, errors.As(err, &statusErr))
+			assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonKeyFetchFailed}))
+		})
+	}
+}
+
+func TestJWKDownloadKeyMaxResponseSize(t *testing.T) {
+	opts, _, _, err := genNewTestServer(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.MaxResponseSize = 64
+	_, err = NewJWKClient(opts, nil).downloadKeys(context.Background())
+	assert.Equal(t, ErrResponseTooLarge, err)
+
+	opts.MaxResponseSize = 0
+	keys, err := NewJWKClient(opts, nil).downloadKeys(context.Background())
+	assert.NoError(t, err, "the default size should be enough")
+	assert.Len(t, keys, 2)
+}
+
+func TestJWKDownloadKeyFiltered(t *testing.T) {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	signing, err := jsonWebKeyRS256.Public().MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutUse := strings.Replace(string(signing), `"use":"sig",`, "", 1)
+	encryption := strings.Replace(strings.Replace(string(signing), `"use":"sig"`, `"use":"enc"`, 1), "keyRS256", "keyEnc", 1)
+	withoutUse = strings.Replace(withoutUse, "keyRS256", "keyNoUse", 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"keys": [%s, %s, %s, {"kid": "noType", "k": "c2VjcmV0"}, {"kty": "unknown", "kid": "unknownType"}]}`, signing, encryption, withoutUse)
+	}))
+	defer ts.Close()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL}, nil)
+
+	keys, err := client.downloadKeys(context.Background())
+	assert.NoError(t, err)
+	var ids []string
+	for _, key := range keys {
+		ids = append(ids, key.KeyID)
+	}
+	assert.Equal(t, []string{"keyRS256", "keyNoUse"}, ids)
+
+	_, err = client.GetKey("keyEnc")
+	assert.True(t, errors.Is(err, ErrNoKeyFound), "keys not meant for signatures should not be cached")
+}
+
+func TestJWKDownloadKeyOnlyFiltered(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"keys": [{"kty": "oct", "use": "enc", "kid": "keyEnc", "k": "c2VjcmV0"}]}`)
+	}))
+	defer ts.Close()
+
+	_, err := NewJWKClient(JWKClientOptions{URI: ts.URL}, nil).downloadKeys(context.Background())
+	assert.Equal(t, ErrNoKeyFound, err)
+}
+
+func TestJWKClientGracePeriod(t *testing.T) {
+	var failing int32
+	ts, counter := genJWKSTestServer(t, failWith(&failing), genTestKeys()...)
+	defer ts.Close()
+
+	clock := newFakeClock()
+	keyCacher := NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{
+		MaxKeyAge:    time.Minute,
+		MaxCacheSize: MaxCacheSizeNoCheck,
+		GracePeriod:  time.Hour,
+		Clock:        clock,
+	})
+	var staleKeys []string
+	client := NewJWKClientWithCache(JWKClientOptions{URI: ts.URL, Clock: clock, OnStaleKey: func(keyID string) {
+		staleKeys = append(staleKeys, keyID)
+	}}, nil, keyCacher)
+
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+
+	atomic.StoreInt32(&failing, http.StatusInternalServerError)
+	clock.Advance(2 * time.Minute)
+
+	key, err := client.GetKey("keyRS256")
+	assert.NoError(t, err, "the expired key should be served while the keys cannot be downloaded")
+	assert.Equal(t, "keyRS256", key.KeyID)
+	assert.Equal(t, uint64(1), keyCacher.Stats().StaleHits)
+	assert.Equal(t, []string{"keyRS256"}, staleKeys)
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter), "the keys should have been downloaded again")
+
+	clock.Advance(time.Hour)
+	_, err = client.GetKey("keyRS256")
+	assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonKeyFetchFailed}), "got: %v", err)
+	clock.Advance(time.Second)
+	_, err = client.GetKey("keyRS256")
+	assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonKeyFetchFailed}), "the failed download should not be forgotten within MinDownloadInterval, got: %v", err)
+	assert.Equal(t, []string{"keyRS256"}, staleKeys)
+
+	atomic.StoreInt32(&failing, 0)
+	clock.Advance(DefaultMinDownloadInterval)
+	_, err = client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), keyCacher.Stats().StaleHits)
+	assert.Equal(t, []string{"keyRS256"}, staleKeys)
+}
@@ -0,0 +1,225 @@
+package auth0
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+// maxUnknownKeys bounds the number of unknown key IDs remembered
+// by a JWKClient, so that spraying random key IDs cannot grow it forever.
+const maxUnknownKeys = 1024
+
+var (
+	// DefaultMinDownloadInterval is the minimum delay between two
+	// downloads of the keys triggered by cache misses.
+	DefaultMinDownloadInterval = 5 * time.Second
+	// DefaultUnknownKeyTTL is how long a key ID missing
+	// from the downloaded keys is remembered.
+	DefaultUnknownKeyTTL = time.Minute
+)
+
+// downloads deduplicates and rate limits the downloads
+// of the keys triggered by cache misses.
+type downloads struct {
+	mu          sync.Mutex
+	inflight    *downloadCall
+	lastStarted time.Time
+	keys        []jose.JSONWebKey
+	err         error
+	unknownKeys map[string]time.Time
+	// validators of the response of the last downloaded keys
+	etag         string
+	lastModified string
+}
+
+// downloadCall is a download of the keys shared
+// by all the concurrent cache misses.
+type downloadCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	// previousStarted is restored as start time of the last
+	// download when every waiter gives up the call
+	previousStarted time.Time
+	abandoned       bool
+	keys            []jose.JSONWebKey
+	err             error
+}
+
+// sharedDownloadKeys downloads the keys, joining the download already
+// in flight if any. The download is aborted only once every caller
+// waiting for it is done. When the previous download started less than
+// MinDownloadInterval ago, the last downloaded keys are returned instead,
+// or the error of the previous download if it failed.
+// It reports whether the keys come from a download started by this call,
+// so after the cache miss of the caller: only the key IDs missing from
+// such keys are known to be unknown.
+func (j *JWKClient) sharedDownloadKeys(ctx context.Context) ([]jose.JSONWebKey, bool, error) {
+	d := &j.downloads
+
+	d.mu.Lock()
+	call := d.inflight
+	started := false
+	if call == nil {
+		now := j.options.Clock.Now()
+		if j.options.MinDownloadInterval > 0 && now.Before(d.lastStarted.Add(j.options.MinDownloadInterval)) {
+			keys, err := d.keys, d.err
+			d.mu.Unlock()
+			if err != nil {
+				return nil, false, err
+			}
+			if len(keys) < 1 {
+				return nil, false, ErrNoKeyFound
+			}
+			return keys, false, nil
+		}
+
+		downloadCtx, cancel := context.WithCancel(context.Background())
+		call = &downloadCall{done: make(chan struct{}), cancel: cancel, previousStarted: d.lastStarted}
+		d.lastStarted = now
+		d.inflight = call
+		started = true
+		go j.runDownload(downloadCtx, call)
+	}
+	call.waiters++
+	d.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.keys, started, call.err
+	case <-ctx.Done():
+		d.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// nobody waits for the keys, the download does not count
+			call.abandoned = true
+			call.cancel()
+			if d.inflight == call {
+				d.inflight = nil
+				d.lastStarted = call.previousStarted
+			}
+		}
+		d.mu.Unlock()
+		return nil, false, ctx.Err()
+	}
+}
+
+func (j *JWKClient) runDownload(ctx context.Context, call *downloadCall) {
+	jwks, err := j.fetchKeys(ctx)
+	call.cancel()
+	if err == nil {
+		j.storeKeys(jwks)
+	}
+	call.keys, call.err = jwks.keys, err
+
+	j.downloads.mu.Lock()
+	if !call.abandoned {
+		j.downloads.err = err
+	}
+	if j.downloads.inflight == call {
+		j.downloads.inflight = nil
+	}
+	j.downloads.mu.Unlock()
+	close(call.done)
+}
+
+// storeKeys records the last downloaded keys, forgets the unknown
+// key IDs they contain and gives them to the cacher if it keeps key sets.
+func (j *JWKClient) storeKeys(jwks jwksResponse) {
+	d := &j.downloads
+	d.mu.Lock()
+	d.keys = jwks.keys
+	d.etag = jwks.etag
+	d.lastModified = jwks.lastModified
+	for _, key := range jwks.keys {
+		delete(d.unknownKeys, key.KeyID)
+	}
+	d.mu.Unlock()
+
+	if keySetCacher, ok := j.keyCacher.(KeySetCacher); ok {
+		j.mu.Lock()
+		keySetCacher.AddKeySet(KeySet{
+			Keys:         jwks.keys,
+			FetchedAt:    j.options.Clock.Now(),
+			ETag:         jwks.etag,
+			LastModified: jwks.lastModified,
+		})
+		j.mu.Unlock()
+	}
+}
+
+// lastKeySet returns the last downloaded keys along with the validators
+// of their response, and reports whether it has any validator. It falls
+// back to the set kept by the cacher if it keeps key sets, such as the
+// set of a previous process.
+func (j *JWKClient) lastKeySet() (KeySet, bool) {
+	d := &j.downloads
+	d.mu.Lock()
+	set := KeySet{Keys: d.keys, ETag: d.etag, LastModified: d.lastModified}
+	d.mu.Unlock()
+	if len(set.Keys) > 0 {
+		return set, set.ETag != "" || set.LastModified != ""
+	}
+
+	if keySetCacher, ok := j.keyCacher.(KeySetCacher); ok {
+		j.mu.Lock()
+		set, ok = keySetCacher.LastKeySet()
+		j.mu.Unlock()
+		if ok && len(set.Keys) > 0 {
+			return set, set.ETag != "" || set.LastModified != ""
+		}
+	}
+	return KeySet{}, false
+}
+
+// isUnknownKey reports whether the key ID was missing
+// from the keys downloaded less than UnknownKeyTTL ago.
+func (j *JWKClient) isUnknownKey(keyID string) bool {
+	if j.options.UnknownKeyTTL <= 0 {
+		return false
+	}
+
+	d := &j.downloads
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	expiry, ok := d.unknownKeys[keyID]
+	if !ok {
+		return false
+	}
+	if j.options.Clock.Now().After(expiry) {
+		delete(d.unknownKeys, keyID)
+		return false
+	}
+	return true
+}
+
+// addUnknownKey remembers the key ID as missing from the downloaded keys.
+func (j *JWKClient) addUnknownKey(keyID string) {
+	if j.options.UnknownKeyTTL <= 0 {
+		return
+	}
+
+	d := &j.downloads
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := j.options.Clock.Now()
+	if d.unknownKeys == nil {
+		d.unknownKeys = map[string]time.Time{}
+	}
+	if len(d.unknownKeys) >= maxUnknownKeys {
+		for id, expiry := range d.unknownKeys {
+			if now.After(expiry) {
+				delete(d.unknownKeys, id)
+			}
+		}
+		if len(d.unknownKeys) >= maxUnknownKeys {
+			d.unknownKeys = map[string]time.Time{}
+		}
+	}
+	d.unknownKeys[keyID] = now.Add(j.options.UnknownKeyTTL)
+}
@@ -0,0 +1,218 @@
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestGetKeyConcurrentMissesShareDownload(t *testing.T) {
+	release := make(chan struct{})
+	ts, counter := genJWKSTestServer(t, waitForRelease(release), genTestKeys()...)
+	defer ts.Close()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL}, nil)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			kid := "keyRS256"
+			if i%2 == 0 {
+				kid = "keyES384"
+			}
+			_, err := client.GetKey(kid)
+			errs <- err
+		}(i)
+	}
+
+	assert.True(t, waitForCalls(counter, 1))
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+}
+
+func TestGetKeyCancelledWaiterDoesNotAbortSharedDownload(t *testing.T) {
+	release := make(chan struct{})
+	ts, counter := genJWKSTestServer(t, waitForRelease(release), genTestKeys()...)
+	defer ts.Close()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL}, nil)
+
+	done := make(chan error)
+	go func() {
+		_, err := client.GetKey("keyRS256")
+		done <- err
+	}()
+	assert.True(t, waitForCalls(counter, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		_, err := client.GetKeyContext(ctx, "keyRS256")
+		cancelled <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	assert.True(t, errors.Is(<-cancelled, context.Canceled))
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+}
+
+func TestGetKeyUnknownKeyTTL(t *testing.T) {
+	ts, counter := genJWKSTestServer(t, nil, genTestKeys()...)
+	defer ts.Close()
+	clock := newFakeClock()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL, UnknownKeyTTL: time.Minute, Clock: clock}, nil)
+
+	for i := 0; i < 5; i++ {
+		_, err := client.GetKey("unknown")
+		assert.True(t, errors.Is(err, ErrNoKeyFound))
+		clock.Advance(DefaultMinDownloadInterval)
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+
+	_, err := client.GetKey("other unknown")
+	assert.True(t, errors.Is(err, ErrNoKeyFound))
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+
+	_, err = client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+
+	clock.Advance(time.Minute)
+	_, err = client.GetKey("unknown")
+	assert.True(t, errors.Is(err, ErrNoKeyFound))
+	assert.Equal(t, uint64(3), atomic.LoadUint64(counter), "the key ID should be forgotten after UnknownKeyTTL")
+}
+
+func TestGetKeyWithoutUnknownKeyTTL(t *testing.T) {
+	ts, counter := genJWKSTestServer(t, nil, genTestKeys()...)
+	defer ts.Close()
+	clock := newFakeClock()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL, UnknownKeyTTL: -1, Clock: clock}, nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetKey("unknown")
+		assert.True(t, errors.Is(err, ErrNoKeyFound))
+		clock.Advance(DefaultMinDownloadInterval)
+	}
+	assert.Equal(t, uint64(3), atomic.LoadUint64(counter))
+}
+
+func TestGetKeyDownloadLimitsByDefault(t *testing.T) {
+	ts, counter := genJWKSTestServer(t, nil, genTestKeys()...)
+	defer ts.Close()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL}, nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetKey("unknown" + strconv.Itoa(i))
+		assert.True(t, errors.Is(err, ErrNoKeyFound))
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+	assert.True(t, client.isUnknownKey("unknown0"))
+	assert.False(t, client.isUnknownKey("unknown1"), "key IDs missing from keys downloaded before the miss should not be remembered")
+}
+
+func TestGetKeyRotatedWithinMinDownloadInterval(t *testing.T) {
+	oldKey := genRSASSAJWK(jose.RS256, "old")
+	newKey := genRSASSAJWK(jose.RS256, "new")
+	var rotated int32
+	ts, counter := genJWKSTestServer(t, func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+		if atomic.LoadInt32(&rotated) == 0 {
+			return false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(JWKS{Keys: []jose.JSONWebKey{oldKey.Public(), newKey.Public()}})
+		return true
+	}, oldKey.Public())
+	defer ts.Close()
+
+	clock := newFakeClock()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL, Clock: clock}, nil)
+	_, err := client.GetKey("old")
+	assert.NoError(t, err)
+
+	atomic.StoreInt32(&rotated, 1)
+	clock.Advance(2 * time.Second)
+	_, err = client.GetKey("new")
+	assert.True(t, errors.Is(err, ErrNoKeyFound))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+
+	clock.Advance(DefaultMinDownloadInterval)
+	key, err := client.GetKey("new")
+	assert.NoError(t, err, "the rotated key should be found once MinDownloadInterval is over")
+	assert.Equal(t, "new", key.KeyID)
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+}
+
+func TestGetKeyCancelledDownloadDoesNotCount(t *testing.T) {
+	release := make(chan struct{})
+	ts, counter := genJWKSTestServer(t, waitForRelease(release), genTestKeys()...)
+	defer ts.Close()
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		_, err := client.GetKeyContext(ctx, "keyRS256")
+		cancelled <- err
+	}()
+	assert.True(t, waitForCalls(counter, 1))
+	cancel()
+	assert.True(t, errors.Is(<-cancelled, context.Canceled))
+	close(release)
+
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err, "the cancelled download should neither be rate limited nor leave its error")
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+}
+
+func TestGetKeyMinDownloadInterval(t *testing.T) {
+	ts, counter := genJWKSTestServer(t, nil, genTestKeys()...)
+	defer ts.Close()
+	opts := JWKClientOptions{URI: ts.URL, MinDownloadInterval: time.Minute}
+	client := NewJWKClientWithCache(opts, nil, NewMemoryKeyCacher(time.Minute, 1))
+
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+
+	_, err = client.GetKey("keyES384")
+	assert.NoError(t, err, "the key should be found in the last downloaded keys")
+
+	for i := 0; i < 3; i++ {
+		_, err = client.GetKey("unknown" + strconv.Itoa(i))
+		assert.True(t, errors.Is(err, ErrNoKeyFound))
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+}
+
+func TestAddUnknownKeyBounded(t *testing.T) {
+	client := NewJWKClient(JWKClientOptions{UnknownKeyTTL: time.Minute}, nil)
+
+	for i := 0; i < maxUnknownKeys+10; i++ {
+		client.addUnknownKey(strconv.Itoa(i))
+	}
+	assert.True(t, len(client.downloads.unknownKeys) <= maxUnknownKeys)
+	assert.True(t, client.isUnknownKey(strconv.Itoa(maxUnknownKeys+9)))
+
+	client.storeKeys(jwksResponse{keys: []jose.JSONWebKey{{KeyID: strconv.Itoa(maxUnknownKeys + 9)}}})
+	assert.False(t, client.isUnknownKey(strconv.Itoa(maxUnknownKeys+9)))
+}
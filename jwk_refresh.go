@@ -0,0 +1,130 @@
+package auth0
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// DefaultRefreshInterval is the delay between two background refreshes
+	// of the keys when the JWKS response has no max-age directive.
+	DefaultRefreshInterval = 15 * time.Minute
+	// DefaultMinRefreshInterval is the minimum delay between two
+	// background refreshes of the keys.
+	DefaultMinRefreshInterval = 30 * time.Second
+)
+
+type refresher struct {
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start launches the background refresh of the keys.
+// The keys are downloaded right away, then again after the max-age
+// of the JWKS response or after RefreshInterval if there is none.
+// The previously downloaded keys keep being served while refreshing,
+// and when a refresh fails.
+// Calling Start on a running client does nothing.
+func (j *JWKClient) Start() {
+	j.refresher.mu.Lock()
+	defer j.refresher.mu.Unlock()
+
+	if j.refresher.stop != nil {
+		return
+	}
+	j.refresher.stop = make(chan struct{})
+	j.refresher.done = make(chan struct{})
+	go j.refreshLoop(j.refresher.stop, j.refresher.done)
+}
+
+// Stop stops the background refresh of the keys and
+// waits for any in-flight download to be aborted.
+// Calling Stop on a stopped client does nothing.
+func (j *JWKClient) Stop() {
+	j.refresher.mu.Lock()
+	defer j.refresher.mu.Unlock()
+
+	if j.refresher.stop == nil {
+		return
+	}
+	close(j.refresher.stop)
+	<-j.refresher.done
+	j.refresher.stop = nil
+	j.refresher.done = nil
+}
+
+func (j *JWKClient) refreshLoop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		timer := time.NewTimer(j.refresh(ctx))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh downloads the keys, stores them into the cache and
+// returns the delay before the next refresh.
+func (j *JWKClient) refresh(ctx context.Context) time.Duration {
+	jwks, err := j.fetchKeys(ctx)
+	if err != nil {
+		return j.options.MinRefreshInterval
+	}
+
+	// cachers keeping key sets are given the whole set by storeKeys
+	j.storeKeys(jwks)
+	if _, ok := j.keyCacher.(KeySetCacher); !ok {
+		j.mu.Lock()
+		for _, key := range jwks.keys {
+			j.keyCacher.Add(key.KeyID, jwks.keys)
+		}
+		j.mu.Unlock()
+	}
+
+	delay := j.options.RefreshInterval
+	if jwks.maxAge > 0 {
+		delay = jwks.maxAge
+	}
+	if delay < j.options.MinRefreshInterval {
+		delay = j.options.MinRefreshInterval
+	}
+	return delay
+}
+
+// parseMaxAge returns the max-age directive of a Cache-Control header,
+// or zero if the header has none or forbids caching.
+func parseMaxAge(cacheControl string) time.Duration {
+	var maxAge time.Duration
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.Trim(directive[len("max-age="):], `"`))
+			if err != nil || seconds < 0 {
+				return 0
+			}
+			maxAge = time.Duration(seconds) * time.Second
+		}
+	}
+	return maxAge
+}
@@ -0,0 +1,108 @@
+package auth0
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitForCalls(counter *uint64, calls uint64) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if atomic.LoadUint64(counter) >= calls {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestJWKClientRefresh(t *testing.T) {
+	ts, counter := genJWKSTestServer(t, nil, genTestKeys()...)
+	defer ts.Close()
+
+	client := NewJWKClient(JWKClientOptions{
+		URI:                ts.URL,
+		RefreshInterval:    10 * time.Millisecond,
+		MinRefreshInterval: time.Millisecond,
+	}, nil)
+	client.Start()
+	client.Start()
+
+	assert.True(t, waitForCalls(counter, 3), "the keys should have been refreshed in background")
+
+	client.Stop()
+	client.Stop()
+
+	calls := atomic.LoadUint64(counter)
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, calls, atomic.LoadUint64(counter), "no download should happen once stopped and keys are cached")
+}
+
+func TestJWKClientRefreshMaxAge(t *testing.T) {
+	ts, counter := genJWKSTestServer(t, func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		return false
+	}, genTestKeys()...)
+	defer ts.Close()
+
+	client := NewJWKClient(JWKClientOptions{
+		URI:                ts.URL,
+		RefreshInterval:    time.Millisecond,
+		MinRefreshInterval: time.Millisecond,
+	}, nil)
+	client.Start()
+	defer client.Stop()
+
+	assert.True(t, waitForCalls(counter, 1))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter), "max-age should take precedence over RefreshInterval")
+}
+
+func TestJWKClientRefreshFailureKeepsKeys(t *testing.T) {
+	var failing int32
+	ts, counter := genJWKSTestServer(t, failWith(&failing), genTestKeys()...)
+	defer ts.Close()
+
+	client := NewJWKClient(JWKClientOptions{
+		URI:                ts.URL,
+		RefreshInterval:    5 * time.Millisecond,
+		MinRefreshInterval: time.Millisecond,
+	}, nil)
+	client.Start()
+	defer client.Stop()
+
+	assert.True(t, waitForCalls(counter, 1))
+	atomic.StoreInt32(&failing, http.StatusServiceUnavailable)
+	assert.True(t, waitForCalls(counter, 4))
+
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+}
+
+func TestParseMaxAge(t *testing.T) {
+	tests := []struct {
+		cacheControl   string
+		expectedMaxAge time.Duration
+	}{
+		{"", 0},
+		{"public", 0},
+		{"max-age=15", 15 * time.Second},
+		{"public, max-age=15, stale-while-revalidate=15", 15 * time.Second},
+		{"Max-Age=\"60\"", time.Minute},
+		{"max-age=-1", 0},
+		{"max-age=abc", 0},
+		{"no-cache, max-age=60", 0},
+		{"max-age=60, no-store", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.cacheControl, func(t *testing.T) {
+			assert.Equal(t, test.expectedMaxAge, parseMaxAge(test.cacheControl))
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package auth0
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// ErrCircuitOpen is returned instead of downloading
+	// the keys while the circuit breaker is open.
+	ErrCircuitOpen = errors.New("JWKS circuit breaker is open")
+
+	// DefaultRetryBaseDelay is the delay before the
+	// first retry when none is configured.
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+	// DefaultRetryMaxDelay caps the delay between two
+	// retries when no cap is configured.
+	DefaultRetryMaxDelay = 2 * time.Second
+	// DefaultBreakerCooldown is how long the circuit breaker
+	// stays open when no cooldown is configured.
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// breaker stops downloading the keys after
+// BreakerThreshold consecutive failed downloads.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// fetchKeys downloads the keys, retrying on transient failures,
+// unless the circuit breaker is open.
+func (j *JWKClient) fetchKeys(ctx context.Context) (jwksResponse, error) {
+	if !j.allowDownload() {
+		return jwksResponse{}, ErrCircuitOpen
+	}
+
+	var jwks jwksResponse
+	var err error
+	for attempt := 0; ; attempt++ {
+		jwks, err = j.requestKeys(ctx)
+		if err == nil || attempt >= j.options.MaxRetries || !isTransientError(ctx, err) {
+			break
+		}
+
+		timer := time.NewTimer(j.retryDelay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return jwksResponse{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if ctx.Err() == nil {
+		j.recordDownload(err)
+	}
+	return jwks, err
+}
+
+// retryDelay returns a random delay up to RetryBaseDelay doubled
+// attempt times, capped by RetryMaxDelay.
+func (j *JWKClient) retryDelay(attempt int) time.Duration {
+	delay := j.options.RetryBaseDelay
+	for i := 0; i < attempt && delay < j.options.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > j.options.RetryMaxDelay {
+		delay = j.options.RetryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// isTransientError reports whether the download may succeed if attempted
+// again: the response was not received or has a 429 or 5xx status.
+func isTransientError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var statusErr *ResponseStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// allowDownload reports whether the circuit breaker lets a download
+// through. Once the cooldown is over, a single download is let through
+// and the breaker stays open until it succeeds.
+func (j *JWKClient) allowDownload() bool {
+	if j.options.BreakerThreshold <= 0 {
+		return true
+	}
+
+	b := &j.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < j.options.BreakerThreshold {
+		return true
+	}
+	now := j.options.Clock.Now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	b.openUntil = now.Add(j.options.BreakerCooldown)
+	return true
+}
+
+// recordDownload counts the consecutive failed downloads,
+// opening the circuit breaker once BreakerThreshold is reached.
+func (j *JWKClient) recordDownload(err error) {
+	if j.options.BreakerThreshold <= 0 {
+		return
+	}
+
+	b := &j.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures == j.options.BreakerThreshold {
+		b.openUntil = j.options.Clock.Now().Add(j.options.BreakerCooldown)
+	}
+}
+
+// breakerOpen reports whether the circuit breaker is open.
+func (j *JWKClient) breakerOpen() bool {
+	if j.options.BreakerThreshold <= 0 {
+		return false
+	}
+
+	b := &j.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= j.options.BreakerThreshold
+}
+
+// staleKey returns the key from the last downloaded keys while the
+// circuit breaker is open, even if it expired from the cache.
+func (j *JWKClient) staleKey(keyID string) (jose.JSONWebKey, bool) {
+	if !j.breakerOpen() {
+		return jose.JSONWebKey{}, false
+	}
+
+	set, _ := j.lastKeySet()
+	for _, key := range set.Keys {
+		if key.KeyID == keyID {
+			j.reportStaleKey(keyID)
+			return key, true
+		}
+	}
+	return jose.JSONWebKey{}, false
+}
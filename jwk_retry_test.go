@@ -0,0 +1,170 @@
+package auth0
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchKeysRetries(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		failures        uint64
+		maxRetries      int
+		expectedCalls   uint64
+		expectedSuccess bool
+	}{
+		{name: "recovered", status: http.StatusServiceUnavailable, failures: 2, maxRetries: 2, expectedCalls: 3, expectedSuccess: true},
+		{name: "rate limited", status: http.StatusTooManyRequests, failures: 1, maxRetries: 1, expectedCalls: 2, expectedSuccess: true},
+		{name: "not recovered", status: http.StatusInternalServerError, failures: 2, maxRetries: 1, expectedCalls: 2},
+		{name: "no retries", status: http.StatusBadGateway, failures: 1, maxRetries: 0, expectedCalls: 1},
+		{name: "not transient", status: http.StatusNotFound, failures: 1, maxRetries: 3, expectedCalls: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts, counter := genJWKSTestServer(t, func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+				if call <= test.failures {
+					w.WriteHeader(test.status)
+					return true
+				}
+				return false
+			}, genTestKeys()...)
+			defer ts.Close()
+
+			client := NewJWKClient(JWKClientOptions{URI: ts.URL, MaxRetries: test.maxRetries, RetryBaseDelay: time.Millisecond}, nil)
+			_, err := client.GetKey("keyRS256")
+			assert.Equal(t, test.expectedSuccess, err == nil, "got: %v", err)
+			assert.Equal(t, test.expectedCalls, atomic.LoadUint64(counter))
+		})
+	}
+}
+
+func TestFetchKeysRetriesNetworkErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
+
+	var ops uint64
+	client := NewJWKClient(JWKClientOptions{
+		URI:            ts.URL,
+		Client:         &http.Client{Transport: &mockRoundTripper{ops: &ops, rt: http.DefaultTransport}},
+		MaxRetries:     2,
+		RetryBaseDelay: time.Millisecond,
+	}, nil)
+
+	_, err := client.downloadKeys(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, uint64(3), atomic.LoadUint64(&ops))
+}
+
+func TestFetchKeysRetryCancelled(t *testing.T) {
+	var failing int32 = http.StatusServiceUnavailable
+	ts, _ := genJWKSTestServer(t, failWith(&failing), genTestKeys()...)
+	defer ts.Close()
+
+	client := NewJWKClient(JWKClientOptions{URI: ts.URL, MaxRetries: 5, RetryBaseDelay: time.Minute, RetryMaxDelay: time.Minute}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.downloadKeys(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < 5*time.Second, "the backoff should stop once the context is done")
+}
+
+func TestRetryDelay(t *testing.T) {
+	client := NewJWKClient(JWKClientOptions{RetryBaseDelay: 10 * time.Millisecond, RetryMaxDelay: 100 * time.Millisecond}, nil)
+
+	for attempt, max := range []time.Duration{10, 20, 40, 80, 100, 100} {
+		for i := 0; i < 50; i++ {
+			delay := client.retryDelay(attempt)
+			assert.True(t, delay >= 0 && delay <= max*time.Millisecond, "attempt %d: %v", attempt, delay)
+		}
+	}
+	assert.True(t, client.retryDelay(1000) <= 100*time.Millisecond)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var failing int32
+	ts, counter := genJWKSTestServer(t, failWith(&failing), genTestKeys()...)
+	defer ts.Close()
+
+	clock := newFakeClock()
+	var staleKeys []string
+	client := NewJWKClientWithCache(JWKClientOptions{
+		URI:              ts.URL,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+		Clock:            clock,
+		OnStaleKey: func(keyID string) {
+			staleKeys = append(staleKeys, keyID)
+		},
+	}, nil, NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{MaxKeyAge: time.Second, MaxCacheSize: MaxCacheSizeNoCheck, Clock: clock}))
+
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+
+	atomic.StoreInt32(&failing, http.StatusInternalServerError)
+	clock.Advance(DefaultMinDownloadInterval)
+
+	_, err = client.GetKey("keyRS256")
+	assert.True(t, errors.Is(err, &ValidationError{Reason: ReasonKeyFetchFailed}), "the breaker should still be closed, got: %v", err)
+
+	clock.Advance(DefaultMinDownloadInterval)
+	key, err := client.GetKey("keyRS256")
+	assert.NoError(t, err, "the expired key should be served once the breaker opens")
+	assert.Equal(t, "keyRS256", key.KeyID)
+	assert.Equal(t, uint64(3), atomic.LoadUint64(counter))
+
+	clock.Advance(DefaultMinDownloadInterval)
+	key, err = client.GetKey("keyES384")
+	assert.NoError(t, err)
+	assert.Equal(t, "keyES384", key.KeyID)
+	clock.Advance(DefaultMinDownloadInterval)
+	_, err = client.GetKey("unknown")
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, uint64(3), atomic.LoadUint64(counter), "no download should happen while the breaker is open")
+	assert.Equal(t, []string{"keyRS256", "keyES384"}, staleKeys, "each use of an expired key should be reported")
+
+	// a single download is let through after the cooldown
+	clock.Advance(2 * time.Minute)
+	_, err = client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	_, err = client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), atomic.LoadUint64(counter))
+
+	atomic.StoreInt32(&failing, 0)
+	clock.Advance(2 * time.Minute)
+	_, err = client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), atomic.LoadUint64(counter))
+	assert.False(t, client.breakerOpen(), "a successful download should close the breaker")
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	var failing int32
+	ts, counter := genJWKSTestServer(t, failWith(&failing), genTestKeys()...)
+	defer ts.Close()
+
+	clock := newFakeClock()
+	client := NewJWKClientWithCache(JWKClientOptions{URI: ts.URL, Clock: clock}, nil,
+		NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{MaxKeyAge: time.Second, MaxCacheSize: MaxCacheSizeNoCheck, Clock: clock}))
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+
+	atomic.StoreInt32(&failing, http.StatusInternalServerError)
+	for i := 0; i < 5; i++ {
+		clock.Advance(DefaultMinDownloadInterval)
+		_, err = client.GetKey("keyRS256")
+		assert.Error(t, err, "expired keys should not be served without circuit breaker")
+	}
+	assert.Equal(t, uint64(6), atomic.LoadUint64(counter))
+}
@@ -0,0 +1,83 @@
+package auth0
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/ed25519"
+	"gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// ErrKeyAlgorithmMismatch is returned when the alg of the
+	// key differs from the alg header of the token.
+	ErrKeyAlgorithmMismatch = errors.New("key algorithm does not match the token algorithm")
+	// ErrKeyUseMismatch is returned when the key
+	// is not meant to verify signatures.
+	ErrKeyUseMismatch = errors.New("key is not meant to verify signatures")
+	// ErrKeyTypeMismatch is returned when the key cannot
+	// verify signatures made with the alg header of the token.
+	ErrKeyTypeMismatch = errors.New("key type does not match the token algorithm")
+)
+
+// checkKeyAlgorithm checks that the key, as returned by a SecretProvider,
+// is meant to verify signatures made with alg. The alg and use of JSON
+// Web Keys are checked along with the type of the key itself. Keys of
+// other types are left to go-jose, which rejects them.
+func checkKeyAlgorithm(key interface{}, alg string) error {
+	switch key := key.(type) {
+	case jose.JSONWebKey:
+		return checkJSONWebKeyAlgorithm(&key, alg)
+	case *jose.JSONWebKey:
+		return checkJSONWebKeyAlgorithm(key, alg)
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS") {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if key.Curve == ecdsaCurve(alg) {
+			return nil
+		}
+	case []byte:
+		if strings.HasPrefix(alg, "HS") {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if alg == string(jose.EdDSA) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return ErrKeyTypeMismatch
+}
+
+func checkJSONWebKeyAlgorithm(key *jose.JSONWebKey, alg string) error {
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return ErrKeyAlgorithmMismatch
+	}
+	if key.Use != "" && key.Use != "sig" {
+		return ErrKeyUseMismatch
+	}
+	if key.Key == nil {
+		return ErrKeyTypeMismatch
+	}
+	return checkKeyAlgorithm(key.Key, alg)
+}
+
+// ecdsaCurve returns the curve of the keys verifying
+// signatures made with alg, nil if alg is not ECDSA.
+func ecdsaCurve(alg string) elliptic.Curve {
+	switch jose.SignatureAlgorithm(alg) {
+	case jose.ES256:
+		return elliptic.P256()
+	case jose.ES384:
+		return elliptic.P384()
+	case jose.ES512:
+		return elliptic.P521()
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package auth0
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestCheckKeyAlgorithm(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	p521Key, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	keys := map[string]interface{}{
+		"RSA":     &rsaKey.PublicKey,
+		"P-256":   &p256Key.PublicKey,
+		"P-384":   &p384Key.PublicKey,
+		"P-521":   &p521Key.PublicKey,
+		"secret":  []byte("secret"),
+		"Ed25519": edKey,
+	}
+	algorithms := map[jose.SignatureAlgorithm]string{
+		jose.RS256: "RSA",
+		jose.RS384: "RSA",
+		jose.RS512: "RSA",
+		jose.PS256: "RSA",
+		jose.PS384: "RSA",
+		jose.PS512: "RSA",
+		jose.ES256: "P-256",
+		jose.ES384: "P-384",
+		jose.ES512: "P-521",
+		jose.HS256: "secret",
+		jose.HS384: "secret",
+		jose.HS512: "secret",
+		jose.EdDSA: "Ed25519",
+	}
+
+	for alg, expectedKey := range algorithms {
+		for name, key := range keys {
+			var expectedError error
+			if name != expectedKey {
+				expectedError = ErrKeyTypeMismatch
+			}
+
+			t.Run(string(alg)+" with "+name, func(t *testing.T) {
+				assert.Equal(t, expectedError, checkKeyAlgorithm(key, string(alg)))
+				assert.Equal(t, expectedError, checkKeyAlgorithm(jose.JSONWebKey{Key: key}, string(alg)))
+				assert.Equal(t, expectedError, checkKeyAlgorithm(&jose.JSONWebKey{Key: key, Use: "sig"}, string(alg)))
+			})
+		}
+	}
+}
+
+func TestCheckJSONWebKeyAlgorithm(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name          string
+		key           jose.JSONWebKey
+		alg           jose.SignatureAlgorithm
+		expectedError error
+	}{
+		{name: "same alg", key: jose.JSONWebKey{Key: &rsaKey.PublicKey, Algorithm: "RS256"}, alg: jose.RS256},
+		{name: "no alg", key: jose.JSONWebKey{Key: &rsaKey.PublicKey}, alg: jose.PS256},
+		{name: "other alg", key: jose.JSONWebKey{Key: &rsaKey.PublicKey, Algorithm: "RS256"}, alg: jose.PS256, expectedError: ErrKeyAlgorithmMismatch},
+		{name: "HS256 with RSA alg", key: jose.JSONWebKey{Key: &rsaKey.PublicKey, Algorithm: "RS256"}, alg: jose.HS256, expectedError: ErrKeyAlgorithmMismatch},
+		{name: "encryption use", key: jose.JSONWebKey{Key: &rsaKey.PublicKey, Use: "enc"}, alg: jose.RS256, expectedError: ErrKeyUseMismatch},
+		{name: "no key", key: jose.JSONWebKey{Algorithm: "RS256"}, alg: jose.RS256, expectedError: ErrKeyTypeMismatch},
+		{name: "unsupported key", key: jose.JSONWebKey{Key: "secret"}, alg: jose.HS256},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedError, checkKeyAlgorithm(test.key, string(test.alg)))
+		})
+	}
+}
+
+func TestValidateKeyAlgorithm(t *testing.T) {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	rsaPublicKey := jsonWebKeyRS256.Public()
+	edPublicKey, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	expiry := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		key            interface{}
+		token          string
+		expectedReason ValidationReason
+	}{
+		{
+			name:  "RS256",
+			key:   rsaPublicKey,
+			token: getTestToken(defaultAudience, defaultIssuer, expiry, jose.RS256, jsonWebKeyRS256),
+		},
+		{
+			name:           "PS256 with an RS256 key",
+			key:            rsaPublicKey,
+			token:          getTestToken(defaultAudience, defaultIssuer, expiry, jose.PS256, jsonWebKeyRS256.Key),
+			expectedReason: ReasonAlgorithm,
+		},
+		{
+			name:           "HS256 with an RSA key",
+			key:            &rsaPublicKey,
+			token:          getTestToken(defaultAudience, defaultIssuer, expiry, jose.HS256, []byte("secret")),
+			expectedReason: ReasonAlgorithm,
+		},
+		{
+			name:  "EdDSA",
+			key:   jose.JSONWebKey{Key: edPublicKey, Use: "sig"},
+			token: getTestToken(defaultAudience, defaultIssuer, expiry, jose.EdDSA, edPrivateKey),
+		},
+		{
+			name:           "EdDSA with an encryption key",
+			key:            jose.JSONWebKey{Key: edPublicKey, Use: "enc"},
+			token:          getTestToken(defaultAudience, defaultIssuer, expiry, jose.EdDSA, edPrivateKey),
+			expectedReason: ReasonAlgorithm,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configuration := NewConfigurationTrustProvider(NewKeyProvider(test.key), defaultAudience, defaultIssuer)
+			validator, req := genTestConfiguration(configuration, test.token)
+
+			_, err := validator.ValidateRequest(req)
+			token, _ := jwt.ParseSigned(test.token)
+			claimsErr := validator.Claims(token, &jwt.Claims{})
+			if test.expectedReason == 0 {
+				assert.NoError(t, err)
+				assert.NoError(t, claimsErr)
+				return
+			}
+			assert.True(t, errors.Is(err, &ValidationError{Reason: test.expectedReason}), "got: %v", err)
+			assert.True(t, errors.Is(claimsErr, &ValidationError{Reason: test.expectedReason}), "got: %v", claimsErr)
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package auth0
 
 import (
+	"container/list"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	jose "gopkg.in/square/go-jose.v2"
@@ -22,10 +25,108 @@ type KeyCacher interface {
 	Add(keyID string, webKeys []jose.JSONWebKey) (*jose.JSONWebKey, error)
 }
 
+// KeySet is a downloaded JSON Web Key Set along
+// with the HTTP validators of its response.
+type KeySet struct {
+	Keys []jose.JSONWebKey `json:"keys"`
+	// FetchedAt is the time the set was downloaded.
+	FetchedAt time.Time `json:"fetched_at"`
+	// ETag is the ETag header of the response.
+	ETag string `json:"etag,omitempty"`
+	// LastModified is the Last-Modified header of the response.
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// KeySetCacher is implemented by the key cachers keeping the
+// whole key set. JWKClient gives them every downloaded set along
+// with its HTTP validators, before adding keys to them.
+type KeySetCacher interface {
+	KeyCacher
+	// AddKeySet replaces the kept set with set.
+	AddKeySet(set KeySet)
+	// LastKeySet returns the kept set, if any.
+	LastKeySet() (KeySet, bool)
+}
+
+// KeyCacherStats counts the lookups and evictions of a key cacher.
+type KeyCacherStats struct {
+	// Hits is the number of keys found by Get.
+	Hits uint64
+	// Misses is the number of keys not found by Get, or found expired.
+	Misses uint64
+	// Evictions is the number of keys removed to make room for others.
+	Evictions uint64
+	// StaleHits is the number of expired keys served by GetStale.
+	StaleHits uint64
+}
+
+// StatsKeyCacher is implemented by the key cachers keeping statistics.
+type StatsKeyCacher interface {
+	KeyCacher
+	Stats() KeyCacherStats
+}
+
+// StaleKeyCacher is implemented by the key cachers keeping expired keys
+// for a grace period. JWKClient falls back to GetStale when the keys
+// cannot be downloaded.
+type StaleKeyCacher interface {
+	KeyCacher
+	// GetStale returns the key, even if expired, until the end
+	// of the grace period. It returns ErrKeyExpired after it.
+	GetStale(keyID string) (*jose.JSONWebKey, error)
+}
+
+// EvictionPolicy selects the key evicted when the cache is full.
+type EvictionPolicy int
+
+const (
+	// EvictLeastRecentlyUsed evicts the key retrieved the longest time ago.
+	EvictLeastRecentlyUsed EvictionPolicy = iota
+	// EvictOldest evicts the key added the longest time ago.
+	EvictOldest
+)
+
+// MemoryKeyCacherOptions configures the cacher
+// created by NewMemoryKeyCacherWithOptions.
+type MemoryKeyCacherOptions struct {
+	// MaxKeyAge is the duration keys are kept after being added.
+	// MaxKeyAgeNoCheck keeps them forever.
+	MaxKeyAge time.Duration
+	// MaxCacheSize is the maximum number of keys kept.
+	// MaxCacheSizeNoCheck, or any negative value, lifts the limit.
+	MaxCacheSize int
+	// EvictionPolicy selects the key evicted when MaxCacheSize is reached.
+	// Defaults to EvictLeastRecentlyUsed.
+	EvictionPolicy EvictionPolicy
+	// GracePeriod is how long keys are kept once expired, to be served
+	// by GetStale when the keys cannot be downloaded. Get still reports
+	// them expired. Zero deletes keys as soon as they expire.
+	GracePeriod time.Duration
+	// Clock tells the time keys are added and retrieved at.
+	// Defaults to the system clock.
+	Clock Clock
+}
+
+// memoryKeyCacher is safe for concurrent use. Hits only take a read
+// lock, which is upgraded to delete expired keys or to move a key
+// to the front of the eviction list.
 type memoryKeyCacher struct {
-	entries      map[string]keyCacherEntry
+	// accessed atomically, kept first for their 64-bit alignment
+	hits      uint64
+	misses    uint64
+	evictions uint64
+	staleHits uint64
+
+	mu sync.RWMutex
+	// entries holds the elements of order, which front is
+	// the key most recently added or, with LRU, used.
+	entries      map[string]*list.Element
+	order        *list.List
 	maxKeyAge    time.Duration
 	maxCacheSize int
+	policy       EvictionPolicy
+	gracePeriod  time.Duration
+	clock        Clock
 }
 
 type keyCacherEntry struct {
@@ -33,57 +134,136 @@ type keyCacherEntry struct {
 	jose.JSONWebKey
 }
 
+// keyCacherElement is the value of the elements of the eviction list.
+type keyCacherElement struct {
+	keyID string
+	entry keyCacherEntry
+}
+
 // NewMemoryKeyCacher creates a new Keycacher interface with option
 // to set max age of cached keys and max size of the cache.
+// The least recently used key is evicted when the cache is full.
 func NewMemoryKeyCacher(maxKeyAge time.Duration, maxCacheSize int) KeyCacher {
+	return NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{
+		MaxKeyAge:    maxKeyAge,
+		MaxCacheSize: maxCacheSize,
+	})
+}
+
+// NewMemoryKeyCacherWithOptions creates an in-memory KeyCacher
+// keeping statistics, configured with options.
+func NewMemoryKeyCacherWithOptions(options MemoryKeyCacherOptions) StatsKeyCacher {
+	if options.Clock == nil {
+		options.Clock = systemClock{}
+	}
 	return &memoryKeyCacher{
-		entries:      map[string]keyCacherEntry{},
-		maxKeyAge:    maxKeyAge,
-		maxCacheSize: maxCacheSize,
+		entries:      map[string]*list.Element{},
+		order:        list.New(),
+		maxKeyAge:    options.MaxKeyAge,
+		maxCacheSize: options.MaxCacheSize,
+		policy:       options.EvictionPolicy,
+		gracePeriod:  options.GracePeriod,
+		clock:        options.Clock,
 	}
 }
 
 func newMemoryPersistentKeyCacher() KeyCacher {
-	return &memoryKeyCacher{
-		entries:      map[string]keyCacherEntry{},
-		maxKeyAge:    MaxKeyAgeNoCheck,
-		maxCacheSize: MaxCacheSizeNoCheck,
-	}
+	return NewMemoryKeyCacher(MaxKeyAgeNoCheck, MaxCacheSizeNoCheck)
 }
 
 // Get obtains a key from the cache, and checks if the key is expired
 func (mkc *memoryKeyCacher) Get(keyID string) (*jose.JSONWebKey, error) {
-	searchKey, ok := mkc.entries[keyID]
+	mkc.mu.RLock()
+	element, ok := mkc.entries[keyID]
+	var searchKey keyCacherEntry
+	var promote bool
 	if ok {
-		if mkc.maxKeyAge == MaxKeyAgeNoCheck || !mkc.keyIsExpired(keyID) {
-			return &searchKey.JSONWebKey, nil
-		}
+		searchKey = element.Value.(*keyCacherElement).entry
+		promote = mkc.policy == EvictLeastRecentlyUsed && mkc.maxCacheSize >= 0 && mkc.order.Front() != element
+	}
+	mkc.mu.RUnlock()
+
+	if !ok {
+		atomic.AddUint64(&mkc.misses, 1)
+		return nil, ErrNoKeyFound
+	}
+	expired := mkc.maxKeyAge != MaxKeyAgeNoCheck && searchKey.isExpired(mkc.clock.Now(), mkc.maxKeyAge)
+	if !expired && !promote {
+		atomic.AddUint64(&mkc.hits, 1)
+		return &searchKey.JSONWebKey, nil
+	}
+
+	mkc.mu.Lock()
+	defer mkc.mu.Unlock()
+
+	// the key may have been evicted or added again since the read lock was released
+	element, ok = mkc.entries[keyID]
+	if !ok {
+		atomic.AddUint64(&mkc.misses, 1)
+		return nil, ErrNoKeyFound
+	}
+	if mkc.maxKeyAge != MaxKeyAgeNoCheck && mkc.keyIsExpired(keyID) {
+		atomic.AddUint64(&mkc.misses, 1)
 		return nil, ErrKeyExpired
 	}
-	return nil, ErrNoKeyFound
+	if promote {
+		mkc.order.MoveToFront(element)
+	}
+	atomic.AddUint64(&mkc.hits, 1)
+	searchKey = element.Value.(*keyCacherElement).entry
+	return &searchKey.JSONWebKey, nil
+}
+
+// GetStale implements the StaleKeyCacher interface.
+func (mkc *memoryKeyCacher) GetStale(keyID string) (*jose.JSONWebKey, error) {
+	mkc.mu.RLock()
+	element, ok := mkc.entries[keyID]
+	var searchKey keyCacherEntry
+	if ok {
+		searchKey = element.Value.(*keyCacherElement).entry
+	}
+	mkc.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNoKeyFound
+	}
+	now := mkc.clock.Now()
+	if mkc.maxKeyAge == MaxKeyAgeNoCheck || !searchKey.isExpired(now, mkc.maxKeyAge) {
+		atomic.AddUint64(&mkc.hits, 1)
+		return &searchKey.JSONWebKey, nil
+	}
+	if searchKey.isExpired(now, mkc.maxKeyAge+mkc.gracePeriod) {
+		return nil, ErrKeyExpired
+	}
+	atomic.AddUint64(&mkc.staleHits, 1)
+	return &searchKey.JSONWebKey, nil
 }
 
 // Add adds a key into the cache and handles overflow
 func (mkc *memoryKeyCacher) Add(keyID string, downloadedKeys []jose.JSONWebKey) (*jose.JSONWebKey, error) {
+	mkc.mu.Lock()
+	defer mkc.mu.Unlock()
+
 	var addingKey jose.JSONWebKey
+	now := mkc.clock.Now()
 
 	for _, key := range downloadedKeys {
 		if key.KeyID == keyID {
 			addingKey = key
 		}
-		if mkc.maxCacheSize == -1 {
-			mkc.entries[key.KeyID] = keyCacherEntry{
-				addedAt:    time.Now(),
+		if mkc.maxCacheSize < 0 {
+			mkc.set(key.KeyID, keyCacherEntry{
+				addedAt:    now,
 				JSONWebKey: key,
-			}
+			})
 		}
 	}
 	if addingKey.Key != nil {
-		if mkc.maxCacheSize != -1 {
-			mkc.entries[addingKey.KeyID] = keyCacherEntry{
-				addedAt:    time.Now(),
+		if mkc.maxCacheSize >= 0 {
+			mkc.set(addingKey.KeyID, keyCacherEntry{
+				addedAt:    now,
 				JSONWebKey: addingKey,
-			}
+			})
 			mkc.handleOverflow()
 		}
 		return &addingKey, nil
@@ -91,26 +271,66 @@ func (mkc *memoryKeyCacher) Add(keyID string, downloadedKeys []jose.JSONWebKey)
 	return nil, ErrNoKeyFound
 }
 
-// keyIsExpired deletes the key from cache if it is expired
-func (mkc *memoryKeyCacher) keyIsExpired(keyID string) bool {
-	if time.Now().After(mkc.entries[keyID].addedAt.Add(mkc.maxKeyAge)) {
+// Stats implements the StatsKeyCacher interface.
+func (mkc *memoryKeyCacher) Stats() KeyCacherStats {
+	return KeyCacherStats{
+		Hits:      atomic.LoadUint64(&mkc.hits),
+		Misses:    atomic.LoadUint64(&mkc.misses),
+		Evictions: atomic.LoadUint64(&mkc.evictions),
+		StaleHits: atomic.LoadUint64(&mkc.staleHits),
+	}
+}
+
+// set stores the entry at the front of the eviction list.
+// It must be called with the write lock held.
+func (mkc *memoryKeyCacher) set(keyID string, entry keyCacherEntry) {
+	if element, ok := mkc.entries[keyID]; ok {
+		element.Value.(*keyCacherElement).entry = entry
+		mkc.order.MoveToFront(element)
+		return
+	}
+	mkc.entries[keyID] = mkc.order.PushFront(&keyCacherElement{keyID, entry})
+}
+
+// remove deletes the key from the cache.
+// It must be called with the write lock held.
+func (mkc *memoryKeyCacher) remove(keyID string) {
+	if element, ok := mkc.entries[keyID]; ok {
+		mkc.order.Remove(element)
 		delete(mkc.entries, keyID)
+	}
+}
+
+// isExpired reports whether the entry is older than maxKeyAge at now.
+func (e keyCacherEntry) isExpired(now time.Time, maxKeyAge time.Duration) bool {
+	return now.After(e.addedAt.Add(maxKeyAge))
+}
+
+// keyIsExpired reports whether the key is expired, deleting it
+// from cache once the grace period is over too.
+// It must be called with the write lock held.
+func (mkc *memoryKeyCacher) keyIsExpired(keyID string) bool {
+	element, ok := mkc.entries[keyID]
+	if !ok {
 		return true
 	}
-	return false
+	entry := element.Value.(*keyCacherElement).entry
+	now := mkc.clock.Now()
+	if !entry.isExpired(now, mkc.maxKeyAge) {
+		return false
+	}
+	if entry.isExpired(now, mkc.maxKeyAge+mkc.gracePeriod) {
+		mkc.remove(keyID)
+	}
+	return true
 }
 
-// handleOverflow deletes the oldest key from the cache if overflowed
+// handleOverflow deletes the keys at the back of the eviction list,
+// the least recently used or the oldest, while the cache is overflowed.
+// It must be called with the write lock held.
 func (mkc *memoryKeyCacher) handleOverflow() {
-	if mkc.maxCacheSize < len(mkc.entries) {
-		var oldestEntryKeyID string
-		var latestAddedTime = time.Now()
-		for entryKeyID, entry := range mkc.entries {
-			if entry.addedAt.Before(latestAddedTime) {
-				latestAddedTime = entry.addedAt
-				oldestEntryKeyID = entryKeyID
-			}
-		}
-		delete(mkc.entries, oldestEntryKeyID)
+	for mkc.maxCacheSize >= 0 && mkc.maxCacheSize < len(mkc.entries) {
+		mkc.remove(mkc.order.Back().Value.(*keyCacherElement).keyID)
+		atomic.AddUint64(&mkc.evictions, 1)
 	}
 }
@@ -0,0 +1,277 @@
+package auth0
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// DefaultKeySetFileName is the name of the file
+	// kept by a file key cacher when none is configured.
+	DefaultKeySetFileName = "jwks.json"
+	// DefaultFileKeyMaxKeyAge is the duration the key set of the
+	// file is used when no MaxKeyAge is configured.
+	DefaultFileKeyMaxKeyAge = 15 * time.Minute
+	// ErrKeySetFileNotPrivate is reported to OnWriteError when the
+	// directory of the key set file, or the file itself, does not
+	// belong to the current user or is writable by other users.
+	ErrKeySetFileNotPrivate = errors.New("key set file is not private to the current user")
+)
+
+// FileKeyCacherOptions configures the cacher
+// created by NewFileKeyCacher.
+type FileKeyCacherOptions struct {
+	// Dir is the directory of the file, created if missing. It must be
+	// private to the current user, as anyone able to write the file
+	// can make the validator trust their own keys. The file is ignored,
+	// and never written, unless both Dir and the file belong to the
+	// current user and are not writable by the group or other users.
+	Dir string
+	// FileName is the name of the file. Use a name per issuer
+	// when several issuers share the directory.
+	// Defaults to DefaultKeySetFileName.
+	FileName string
+	// MaxKeyAge is the duration the key set is used after being
+	// downloaded, even by other processes. MaxKeyAgeNoCheck uses it
+	// until it is replaced. Defaults to DefaultFileKeyMaxKeyAge.
+	MaxKeyAge time.Duration
+	// OnWriteError is called when the file cannot be written.
+	// Writing failures do not fail adding keys.
+	OnWriteError func(err error)
+	// Clock tells the time the key set is checked for expiry at.
+	// Defaults to the system clock.
+	Clock Clock
+}
+
+// fileKeyCacher keeps the last downloaded key set in a file,
+// and in memory for lookups.
+type fileKeyCacher struct {
+	// accessed atomically, kept first for their 64-bit alignment
+	hits   uint64
+	misses uint64
+
+	// mu serializes the writes of the file
+	mu       sync.Mutex
+	snapshot atomic.Value // *keySnapshot
+	path     string
+	options  FileKeyCacherOptions
+}
+
+// NewFileKeyCacher creates a KeySetCacher keeping the last downloaded
+// key set, its download time and its HTTP validators in a file, so
+// that short-lived processes can reuse it instead of downloading it.
+// The file is read once by NewFileKeyCacher and replaced atomically
+// on each download. A corrupted or insecure file, or a set fetched
+// in the future, is ignored and the keys are downloaded again.
+func NewFileKeyCacher(options FileKeyCacherOptions) KeySetCacher {
+	if options.FileName == "" {
+		options.FileName = DefaultKeySetFileName
+	}
+	if options.MaxKeyAge == 0 {
+		options.MaxKeyAge = DefaultFileKeyMaxKeyAge
+	}
+	if options.Clock == nil {
+		options.Clock = systemClock{}
+	}
+
+	fkc := &fileKeyCacher{
+		path:    filepath.Join(options.Dir, options.FileName),
+		options: options,
+	}
+	if set, err := readKeySet(fkc.path); err == nil && !set.FetchedAt.After(options.Clock.Now()) {
+		fkc.snapshot.Store(newKeySnapshot(set))
+	}
+	return fkc
+}
+
+// Get obtains a key from the key set, and checks if the set is expired.
+func (fkc *fileKeyCacher) Get(keyID string) (*jose.JSONWebKey, error) {
+	snapshot, _ := fkc.snapshot.Load().(*keySnapshot)
+	if snapshot == nil {
+		atomic.AddUint64(&fkc.misses, 1)
+		return nil, ErrNoKeyFound
+	}
+
+	key, err := snapshot.get(keyID, fkc.options.MaxKeyAge, fkc.options.Clock.Now())
+	if err != nil {
+		atomic.AddUint64(&fkc.misses, 1)
+		return nil, err
+	}
+	atomic.AddUint64(&fkc.hits, 1)
+	return key, nil
+}
+
+// Add replaces the key set with downloadedKeys, unless it holds
+// the same keys, and returns the key identified by keyID.
+func (fkc *fileKeyCacher) Add(keyID string, downloadedKeys []jose.JSONWebKey) (*jose.JSONWebKey, error) {
+	snapshot, _ := fkc.snapshot.Load().(*keySnapshot)
+	if snapshot == nil || !sameKeys(snapshot.set.Keys, downloadedKeys) {
+		fkc.AddKeySet(KeySet{Keys: downloadedKeys, FetchedAt: fkc.options.Clock.Now()})
+	}
+
+	for _, key := range downloadedKeys {
+		if key.KeyID == keyID {
+			return &key, nil
+		}
+	}
+	return nil, ErrNoKeyFound
+}
+
+// AddKeySet implements the KeySetCacher interface.
+func (fkc *fileKeyCacher) AddKeySet(set KeySet) {
+	fkc.mu.Lock()
+	defer fkc.mu.Unlock()
+
+	fkc.snapshot.Store(newKeySnapshot(set))
+	if err := writeKeySet(fkc.path, set); err != nil && fkc.options.OnWriteError != nil {
+		fkc.options.OnWriteError(err)
+	}
+}
+
+// LastKeySet implements the KeySetCacher interface.
+func (fkc *fileKeyCacher) LastKeySet() (KeySet, bool) {
+	snapshot, _ := fkc.snapshot.Load().(*keySnapshot)
+	if snapshot == nil {
+		return KeySet{}, false
+	}
+	return snapshot.set, true
+}
+
+// Stats implements the StatsKeyCacher interface.
+func (fkc *fileKeyCacher) Stats() KeyCacherStats {
+	return KeyCacherStats{
+		Hits:   atomic.LoadUint64(&fkc.hits),
+		Misses: atomic.LoadUint64(&fkc.misses),
+	}
+}
+
+// readKeySet reads the set of the file at path,
+// provided that it and its directory are private.
+func readKeySet(path string) (KeySet, error) {
+	if _, err := checkPrivatePath(filepath.Dir(path), true); err != nil {
+		return KeySet{}, err
+	}
+	info, err := checkPrivatePath(path, false)
+	if err != nil {
+		return KeySet{}, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return KeySet{}, err
+	}
+	defer file.Close()
+	// the file may have been replaced since it was checked
+	openedInfo, err := file.Stat()
+	if err != nil {
+		return KeySet{}, err
+	}
+	if !os.SameFile(info, openedInfo) {
+		return KeySet{}, ErrKeySetFileNotPrivate
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return KeySet{}, err
+	}
+
+	set := KeySet{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return KeySet{}, err
+	}
+	if len(set.Keys) < 1 {
+		return KeySet{}, ErrNoKeyFound
+	}
+	return set, nil
+}
+
+// writeKeySet writes the set to a temporary file renamed
+// to path, so that readers never see a partial file.
+func writeKeySet(path string, set KeySet) error {
+	data, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// MkdirAll keeps the permissions of an existing directory
+	if _, err := checkPrivatePath(dir, true); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// checkPrivatePath checks that path is a directory if dir is set,
+// or else a regular file, and that it is private to the current user.
+// Symbolic links are not followed.
+func checkPrivatePath(path string, dir bool) (os.FileInfo, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if dir && !info.IsDir() || !dir && !info.Mode().IsRegular() {
+		return nil, ErrKeySetFileNotPrivate
+	}
+	if err := checkPrivate(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// sameKeys reports whether a and b hold the same keys, in the same
+// order, comparing their key IDs and key material.
+func sameKeys(a, b []jose.JSONWebKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].KeyID != b[i].KeyID || !sameKeyMaterial(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKeyMaterial compares the secrets of symmetric keys
+// and the thumbprints of the others.
+func sameKeyMaterial(a, b jose.JSONWebKey) bool {
+	if secret, ok := a.Key.([]byte); ok {
+		other, ok := b.Key.([]byte)
+		return ok && bytes.Equal(secret, other)
+	}
+	thumbprint, err := a.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return false
+	}
+	other, err := b.Thumbprint(crypto.SHA256)
+	return err == nil && bytes.Equal(thumbprint, other)
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package auth0
+
+import "os"
+
+// checkPrivate does not check the access control lists of Windows,
+// nor the permissions of Plan 9: Dir must already be private there.
+func checkPrivate(info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,221 @@
+package auth0
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func genTestKeySetDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "go-auth0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFileKeyCacher(t *testing.T) {
+	dir := genTestKeySetDir(t)
+	defer os.RemoveAll(dir)
+
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	jsonWebKeyES384 := genECDSAJWK(jose.ES384, "keyES384")
+	set := KeySet{
+		Keys:         []jose.JSONWebKey{jsonWebKeyRS256.Public(), jsonWebKeyES384.Public()},
+		FetchedAt:    time.Now(),
+		ETag:         `"v1"`,
+		LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+	}
+
+	fkc := NewFileKeyCacher(FileKeyCacherOptions{Dir: filepath.Join(dir, "cache"), MaxKeyAge: time.Minute})
+	_, ok := fkc.LastKeySet()
+	assert.False(t, ok)
+	_, err := fkc.Get("keyRS256")
+	assert.Equal(t, ErrNoKeyFound, err)
+
+	fkc.AddKeySet(set)
+	key, err := fkc.Get("keyES384")
+	assert.NoError(t, err)
+	assert.Equal(t, "keyES384", key.KeyID)
+
+	// another process reading the file
+	other := NewFileKeyCacher(FileKeyCacherOptions{Dir: filepath.Join(dir, "cache"), MaxKeyAge: time.Minute})
+	key, err = other.Get("keyRS256")
+	assert.NoError(t, err)
+	assert.Equal(t, jsonWebKeyRS256.Public().Key, key.Key)
+
+	lastSet, ok := other.LastKeySet()
+	assert.True(t, ok)
+	assert.Equal(t, set.ETag, lastSet.ETag)
+	assert.Equal(t, set.LastModified, lastSet.LastModified)
+	assert.True(t, set.FetchedAt.Equal(lastSet.FetchedAt))
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "cache"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "no temporary file should be left")
+	assert.Equal(t, DefaultKeySetFileName, files[0].Name())
+}
+
+func TestFileKeyCacherMaxKeyAge(t *testing.T) {
+	dir := genTestKeySetDir(t)
+	defer os.RemoveAll(dir)
+
+	keys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}}
+	clock := newFakeClock()
+
+	tests := []struct {
+		name          string
+		fetchedAt     time.Time
+		maxKeyAge     time.Duration
+		expectedError error
+	}{
+		{name: "not expired", fetchedAt: clock.Now(), maxKeyAge: time.Minute},
+		{name: "expired", fetchedAt: clock.Now().Add(-time.Hour), maxKeyAge: time.Minute, expectedError: ErrKeyExpired},
+		{name: "never expires", fetchedAt: clock.Now().Add(-time.Hour), maxKeyAge: MaxKeyAgeNoCheck},
+		{name: "default", fetchedAt: clock.Now().Add(-time.Minute)},
+		{name: "default expired", fetchedAt: clock.Now().Add(-time.Hour), expectedError: ErrKeyExpired},
+		{name: "fetched in the future", fetchedAt: clock.Now().Add(time.Hour), maxKeyAge: MaxKeyAgeNoCheck, expectedError: ErrNoKeyFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			NewFileKeyCacher(FileKeyCacherOptions{Dir: dir}).AddKeySet(KeySet{Keys: keys, FetchedAt: test.fetchedAt})
+
+			_, err := NewFileKeyCacher(FileKeyCacherOptions{Dir: dir, MaxKeyAge: test.maxKeyAge, Clock: clock}).Get("test1")
+			assert.Equal(t, test.expectedError, err)
+		})
+	}
+}
+
+func TestFileKeyCacherCorruptedFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "truncated", content: `{"keys": [{"kty": "oct", "k": "c2VjcmV0", "kid": "test1"}`},
+		{name: "invalid key", content: `{"keys": [{"kty": "unknown", "kid": "test1"}]}`},
+		{name: "no keys", content: `{"keys": []}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := genTestKeySetDir(t)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, DefaultKeySetFileName)
+			assert.NoError(t, ioutil.WriteFile(path, []byte(test.content), 0600))
+
+			ts, counter := genJWKSTestServer(t, nil, genTestKeys()...)
+			defer ts.Close()
+			opts := JWKClientOptions{URI: ts.URL}
+			client := NewJWKClientWithCache(opts, nil, NewFileKeyCacher(FileKeyCacherOptions{Dir: dir, MaxKeyAge: time.Minute}))
+
+			_, err := client.GetKey("keyRS256")
+			assert.NoError(t, err, "the keys should be downloaded again")
+			assert.Equal(t, uint64(1), atomic.LoadUint64(counter))
+
+			set, err := readKeySet(path)
+			assert.NoError(t, err, "the file should have been replaced")
+			assert.Len(t, set.Keys, 2)
+		})
+	}
+}
+
+func TestFileKeyCacherNotPrivate(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("the permissions are not checked on " + runtime.GOOS)
+	}
+	keys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}}
+
+	tests := []struct {
+		name          string
+		prepare       func(dir string) error
+		dirNotPrivate bool
+	}{
+		{name: "world-writable dir", prepare: func(dir string) error { return os.Chmod(dir, 0777) }, dirNotPrivate: true},
+		{name: "group-writable dir", prepare: func(dir string) error { return os.Chmod(dir, 0770) }, dirNotPrivate: true},
+		{name: "world-writable file", prepare: func(dir string) error {
+			return os.Chmod(filepath.Join(dir, DefaultKeySetFileName), 0666)
+		}},
+		{name: "symlink", prepare: func(dir string) error {
+			path := filepath.Join(dir, DefaultKeySetFileName)
+			if err := os.Rename(path, path+".target"); err != nil {
+				return err
+			}
+			return os.Symlink(path+".target", path)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := genTestKeySetDir(t)
+			defer os.RemoveAll(dir)
+			NewFileKeyCacher(FileKeyCacherOptions{Dir: dir}).AddKeySet(KeySet{Keys: keys, FetchedAt: time.Now()})
+			assert.NoError(t, test.prepare(dir))
+
+			var reported []error
+			fkc := NewFileKeyCacher(FileKeyCacherOptions{Dir: dir, OnWriteError: func(err error) {
+				reported = append(reported, err)
+			}})
+			_, err := fkc.Get("test1")
+			assert.Equal(t, ErrNoKeyFound, err, "the file should be ignored")
+
+			fkc.AddKeySet(KeySet{Keys: keys, FetchedAt: time.Now()})
+			if test.dirNotPrivate {
+				assert.Equal(t, []error{ErrKeySetFileNotPrivate}, reported, "the file should not be written")
+			}
+		})
+	}
+}
+
+func TestFileKeyCacherWriteError(t *testing.T) {
+	dir := genTestKeySetDir(t)
+	defer os.RemoveAll(dir)
+	notDir := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(notDir, nil, 0600))
+
+	var reported []error
+	fkc := NewFileKeyCacher(FileKeyCacherOptions{Dir: notDir, MaxKeyAge: time.Minute, OnWriteError: func(err error) {
+		reported = append(reported, err)
+	}})
+
+	key, err := fkc.Add("test1", []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}})
+	assert.NoError(t, err, "failing to write the file should not fail Add")
+	assert.Equal(t, "test1", key.KeyID)
+	assert.Len(t, reported, 1)
+
+	_, err = fkc.Get("test1")
+	assert.NoError(t, err, "the keys should still be kept in memory")
+}
+
+func TestJWKClientWithFileKeyCacher(t *testing.T) {
+	dir := genTestKeySetDir(t)
+	defer os.RemoveAll(dir)
+
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	ts, counter := genJWKSTestServer(t, func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		return false
+	}, jsonWebKeyRS256.Public())
+	defer ts.Close()
+
+	options := FileKeyCacherOptions{Dir: dir, FileName: "tenant.json", MaxKeyAge: time.Minute}
+	for i := 0; i < 3; i++ {
+		// a new process for each invocation
+		client := NewJWKClientWithCache(JWKClientOptions{URI: ts.URL}, nil, NewFileKeyCacher(options))
+		_, err := client.GetKey("keyRS256")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter), "the keys should be read from the file")
+
+	set, err := readKeySet(filepath.Join(dir, "tenant.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, `"v1"`, set.ETag)
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", set.LastModified)
+	assert.WithinDuration(t, time.Now(), set.FetchedAt, time.Minute)
+}
@@ -0,0 +1,22 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package auth0
+
+import (
+	"os"
+	"syscall"
+)
+
+// checkPrivate checks that info describes a file or directory owned
+// by the current user, which other users cannot write to.
+func checkPrivate(info os.FileInfo) error {
+	if info.Mode().Perm()&0022 != 0 {
+		return ErrKeySetFileNotPrivate
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Getuid() {
+		return ErrKeySetFileNotPrivate
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// DefaultSharedKeyPrefix is the prefix of the entries
+	// of the store when none is configured.
+	DefaultSharedKeyPrefix = "go-auth0:jwk:"
+	// DefaultSharedKeyTTL is the duration the keys are
+	// kept in the store when none is configured.
+	DefaultSharedKeyTTL = 15 * time.Minute
+	// DefaultSharedKeyTimeout bounds each call
+	// to the store when no timeout is configured.
+	DefaultSharedKeyTimeout = time.Second
+	// DefaultSharedKeyLocalMaxKeyAge is the duration the keys are served
+	// from the local copy, without reading the store, when none is configured.
+	DefaultSharedKeyLocalMaxKeyAge = time.Minute
+)
+
+// KeyStore is a key-value store shared by several processes,
+// such as Redis, in which a shared key cacher keeps the keys.
+type KeyStore interface {
+	// Get returns the value of key, or ErrNoKeyFound if there is none.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set sets the value of key, which expires after ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// SharedKeyCacherOptions configures the cacher
+// created by NewSharedKeyCacher.
+type SharedKeyCacherOptions struct {
+	// Prefix is prepended to the key IDs to name the entries of the store.
+	// Use a prefix per issuer when several issuers share the store.
+	// Defaults to DefaultSharedKeyPrefix.
+	Prefix string
+	// TTL is the duration the keys are kept in the store.
+	// Defaults to DefaultSharedKeyTTL.
+	TTL time.Duration
+	// Timeout bounds each call to the store.
+	// Defaults to DefaultSharedKeyTimeout.
+	Timeout time.Duration
+	// OnStoreError is called when the keys cannot be written to the store.
+	// Writing failures do not fail Add, which still returns the key.
+	OnStoreError func(err error)
+	// LocalMaxKeyAge is the duration the keys read from the store or
+	// added are served from a local copy without reading the store again.
+	// When the store fails, the local copy is served for up to TTL more.
+	// Defaults to DefaultSharedKeyLocalMaxKeyAge, and is at most TTL.
+	LocalMaxKeyAge time.Duration
+	// Clock tells the time the local copies are checked for expiry at.
+	// Defaults to the system clock.
+	Clock Clock
+}
+
+// sharedKeyCacher keeps the keys in a KeyStore as JSON,
+// and a copy of them in memory.
+type sharedKeyCacher struct {
+	store   KeyStore
+	local   *memoryKeyCacher
+	options SharedKeyCacherOptions
+}
+
+// NewSharedKeyCacher creates a KeyCacher keeping the keys in store, so
+// that the processes sharing it download the keys only once between them.
+// Every key of the downloaded set is written to the store.
+// The keys are also kept in memory for LocalMaxKeyAge, so that
+// the store is not read on every lookup.
+func NewSharedKeyCacher(store KeyStore, options SharedKeyCacherOptions) KeyCacher {
+	if options.Prefix == "" {
+		options.Prefix = DefaultSharedKeyPrefix
+	}
+	if options.TTL == 0 {
+		options.TTL = DefaultSharedKeyTTL
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultSharedKeyTimeout
+	}
+	if options.LocalMaxKeyAge <= 0 {
+		options.LocalMaxKeyAge = DefaultSharedKeyLocalMaxKeyAge
+	}
+	if options.LocalMaxKeyAge > options.TTL {
+		options.LocalMaxKeyAge = options.TTL
+	}
+	if options.Clock == nil {
+		options.Clock = systemClock{}
+	}
+
+	local := NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{
+		MaxKeyAge:    options.LocalMaxKeyAge,
+		MaxCacheSize: MaxCacheSizeNoCheck,
+		GracePeriod:  options.TTL,
+		Clock:        options.Clock,
+	})
+	return &sharedKeyCacher{store, local.(*memoryKeyCacher), options}
+}
+
+// Get obtains a key from the local copy, or else from the store.
+// When the store fails, the expired local copy is returned if it is
+// still within TTL, otherwise the error of the store is returned as
+// is and the key is downloaded again.
+func (skc *sharedKeyCacher) Get(keyID string) (*jose.JSONWebKey, error) {
+	if key, err := skc.local.Get(keyID); err == nil {
+		return key, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), skc.options.Timeout)
+	defer cancel()
+
+	value, err := skc.store.Get(ctx, skc.options.Prefix+keyID)
+	if err != nil {
+		if err != ErrNoKeyFound {
+			if key, staleErr := skc.local.GetStale(keyID); staleErr == nil {
+				return key, nil
+			}
+		}
+		return nil, err
+	}
+
+	key := jose.JSONWebKey{}
+	if err := json.Unmarshal(value, &key); err != nil {
+		return nil, err
+	}
+	return skc.local.Add(keyID, []jose.JSONWebKey{key})
+}
+
+// Add writes the downloaded keys to the store and to the
+// local copy, and returns the key identified by keyID.
+func (skc *sharedKeyCacher) Add(keyID string, downloadedKeys []jose.JSONWebKey) (*jose.JSONWebKey, error) {
+	var addingKey *jose.JSONWebKey
+	skc.local.Add(keyID, downloadedKeys)
+
+	ctx, cancel := context.WithTimeout(context.Background(), skc.options.Timeout)
+	defer cancel()
+
+	for i, key := range downloadedKeys {
+		if key.KeyID == keyID {
+			addingKey = &downloadedKeys[i]
+		}
+		if err := skc.set(ctx, key); err != nil && skc.options.OnStoreError != nil {
+			skc.options.OnStoreError(err)
+		}
+	}
+
+	if addingKey == nil {
+		return nil, ErrNoKeyFound
+	}
+	key := *addingKey
+	return &key, nil
+}
+
+func (skc *sharedKeyCacher) set(ctx context.Context, key jose.JSONWebKey) error {
+	value, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return skc.store.Set(ctx, skc.options.Prefix+key.KeyID, value, skc.options.TTL)
+}
@@ -0,0 +1,188 @@
+package auth0
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+type fakeKeyStoreEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// fakeKeyStore is an in-process KeyStore expiring its entries by clock.
+type fakeKeyStore struct {
+	mu      sync.Mutex
+	entries map[string]fakeKeyStoreEntry
+	err     error
+	clock   *fakeClock
+}
+
+func newFakeKeyStore() *fakeKeyStore {
+	return &fakeKeyStore{entries: map[string]fakeKeyStoreEntry{}, clock: newFakeClock()}
+}
+
+func (s *fakeKeyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	entry, ok := s.entries[key]
+	if !ok || s.clock.Now().After(entry.expiresAt) {
+		return nil, ErrNoKeyFound
+	}
+	return entry.value, nil
+}
+
+func (s *fakeKeyStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.entries[key] = fakeKeyStoreEntry{value, s.clock.Now().Add(ttl)}
+	return nil
+}
+
+func TestSharedKeyCacher(t *testing.T) {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	jsonWebKeyES384 := genECDSAJWK(jose.ES384, "keyES384")
+	downloadedKeys := []jose.JSONWebKey{jsonWebKeyRS256.Public(), jsonWebKeyES384.Public()}
+
+	store := newFakeKeyStore()
+	skc := NewSharedKeyCacher(store, SharedKeyCacherOptions{})
+
+	_, err := skc.Get("keyRS256")
+	assert.Equal(t, ErrNoKeyFound, err)
+
+	key, err := skc.Add("keyRS256", downloadedKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, "keyRS256", key.KeyID)
+	assert.Len(t, store.entries, 2)
+	assert.Contains(t, store.entries, DefaultSharedKeyPrefix+"keyES384")
+
+	// another process sharing the store
+	other := NewSharedKeyCacher(store, SharedKeyCacherOptions{})
+	key, err = other.Get("keyES384")
+	assert.NoError(t, err)
+	assert.Equal(t, "keyES384", key.KeyID)
+	assert.Equal(t, jose.ES384, jose.SignatureAlgorithm(key.Algorithm))
+	assert.Equal(t, downloadedKeys[1].Key, key.Key)
+
+	_, err = skc.Add("unknown", downloadedKeys)
+	assert.Equal(t, ErrNoKeyFound, err)
+}
+
+func TestSharedKeyCacherOptions(t *testing.T) {
+	downloadedKeys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}}
+
+	store := newFakeKeyStore()
+	skc := NewSharedKeyCacher(store, SharedKeyCacherOptions{Prefix: "tenant:", TTL: time.Minute, Clock: store.clock})
+	_, err := skc.Add("test1", downloadedKeys)
+	assert.NoError(t, err)
+	assert.Contains(t, store.entries, "tenant:test1")
+
+	store.clock.Advance(2 * time.Minute)
+	_, err = skc.Get("test1")
+	assert.Equal(t, ErrNoKeyFound, err, "the key should have expired from the store")
+}
+
+func TestSharedKeyCacherStoreErrors(t *testing.T) {
+	downloadedKeys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}}
+	storeErr := errors.New("store is down")
+
+	store := newFakeKeyStore()
+	store.err = storeErr
+	var reported []error
+	skc := NewSharedKeyCacher(store, SharedKeyCacherOptions{OnStoreError: func(err error) {
+		reported = append(reported, err)
+	}})
+
+	_, err := skc.Get("test1")
+	assert.Equal(t, storeErr, err, "the store error should be returned without local copy")
+
+	key, err := skc.Add("test1", downloadedKeys)
+	assert.NoError(t, err, "failing to write to the store should not fail Add")
+	assert.Equal(t, "test1", key.KeyID)
+	assert.Equal(t, []error{storeErr}, reported)
+
+	store.err = nil
+	store.entries["go-auth0:jwk:test1"] = fakeKeyStoreEntry{[]byte("not json"), store.clock.Now().Add(time.Minute)}
+	_, err = NewSharedKeyCacher(store, SharedKeyCacherOptions{}).Get("test1")
+	assert.Error(t, err)
+}
+
+func TestSharedKeyCacherLocalCopy(t *testing.T) {
+	downloadedKeys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}}
+
+	clock := newFakeClock()
+	store := newFakeKeyStore()
+	skc := NewSharedKeyCacher(store, SharedKeyCacherOptions{LocalMaxKeyAge: time.Minute, Clock: clock})
+	other := NewSharedKeyCacher(store, SharedKeyCacherOptions{LocalMaxKeyAge: time.Minute, Clock: clock})
+	_, err := skc.Add("test1", downloadedKeys)
+	assert.NoError(t, err)
+	_, err = other.Get("test1")
+	assert.NoError(t, err)
+
+	store.err = errors.New("store is down")
+	for _, cacher := range []KeyCacher{skc, other} {
+		_, err = cacher.Get("test1")
+		assert.NoError(t, err, "the local copy should be served without reading the store")
+	}
+
+	clock.Advance(2 * time.Minute)
+	for _, cacher := range []KeyCacher{skc, other} {
+		key, err := cacher.Get("test1")
+		assert.NoError(t, err, "the expired local copy should be served while the store fails")
+		assert.Equal(t, "test1", key.KeyID)
+	}
+
+	clock.Advance(DefaultSharedKeyTTL)
+	_, err = skc.Get("test1")
+	assert.Equal(t, store.err, err, "the local copy should not outlive the TTL")
+}
+
+func TestJWKClientWithFailingKeyStore(t *testing.T) {
+	ts, counter := genJWKSTestServer(t, nil, genTestKeys()...)
+	defer ts.Close()
+	opts := JWKClientOptions{URI: ts.URL}
+
+	store := newFakeKeyStore()
+	store.err = errors.New("store is down")
+	var reported int32
+	client := NewJWKClientWithCache(opts, nil, NewSharedKeyCacher(store, SharedKeyCacherOptions{OnStoreError: func(error) {
+		atomic.AddInt32(&reported, 1)
+	}}))
+	for i := 0; i < 10; i++ {
+		_, err := client.GetKey("keyRS256")
+		assert.NoError(t, err)
+		_, err = client.GetKey("keyES384")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter), "a failing store should not download the keys on every request")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&reported))
+}
+
+func TestJWKClientsSharingKeyStore(t *testing.T) {
+	ts, counter := genJWKSTestServer(t, nil, genTestKeys()...)
+	defer ts.Close()
+	opts := JWKClientOptions{URI: ts.URL}
+
+	store := newFakeKeyStore()
+	for i := 0; i < 5; i++ {
+		client := NewJWKClientWithCache(opts, nil, NewSharedKeyCacher(store, SharedKeyCacherOptions{}))
+		_, err := client.GetKey("keyRS256")
+		assert.NoError(t, err)
+		_, err = client.GetKey("keyES384")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter), "the replicas should share the downloaded keys")
+}
@@ -0,0 +1,138 @@
+package auth0
+
+import (
+	"sync/atomic"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// SnapshotKeyCacherOptions configures the cacher
+// created by NewSnapshotKeyCacherWithOptions.
+type SnapshotKeyCacherOptions struct {
+	// MaxKeyAge is the duration the key set is kept after being
+	// fetched. MaxKeyAgeNoCheck keeps it until it is replaced.
+	MaxKeyAge time.Duration
+	// Clock tells the time the key set is added and checked
+	// for expiry at. Defaults to the system clock.
+	Clock Clock
+}
+
+// snapshotKeyCacher keeps the last key set it was given as a whole.
+// Lookups load the current snapshot without locking.
+type snapshotKeyCacher struct {
+	// accessed atomically, kept first for their 64-bit alignment
+	hits   uint64
+	misses uint64
+
+	snapshot  atomic.Value // *keySnapshot
+	maxKeyAge time.Duration
+	clock     Clock
+}
+
+// keySnapshot is a key set indexed by key ID.
+type keySnapshot struct {
+	set  KeySet
+	keys map[string]jose.JSONWebKey
+}
+
+func newKeySnapshot(set KeySet) *keySnapshot {
+	keys := make(map[string]jose.JSONWebKey, len(set.Keys))
+	for _, key := range set.Keys {
+		keys[key.KeyID] = key
+	}
+	return &keySnapshot{set, keys}
+}
+
+// get returns the key identified by keyID, unless the set
+// was fetched more than maxKeyAge before now.
+func (s *keySnapshot) get(keyID string, maxKeyAge time.Duration, now time.Time) (*jose.JSONWebKey, error) {
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, ErrNoKeyFound
+	}
+	if maxKeyAge != MaxKeyAgeNoCheck && now.After(s.set.FetchedAt.Add(maxKeyAge)) {
+		return nil, ErrKeyExpired
+	}
+	return &key, nil
+}
+
+// NewSnapshotKeyCacher creates a KeyCacher keeping the whole key set
+// given to Add, so that any of its keys is found without downloading
+// the set again. Each Add replaces the set, dropping the keys removed
+// from it, such as revoked keys. It is also a KeySetCacher, given
+// each set downloaded by JWKClient.
+// The set expires maxKeyAge after being fetched, MaxKeyAgeNoCheck
+// keeps it until it is replaced.
+func NewSnapshotKeyCacher(maxKeyAge time.Duration) StatsKeyCacher {
+	return NewSnapshotKeyCacherWithOptions(SnapshotKeyCacherOptions{MaxKeyAge: maxKeyAge})
+}
+
+// NewSnapshotKeyCacherWithOptions creates a KeyCacher keeping the
+// whole key set, like NewSnapshotKeyCacher, configured with options.
+func NewSnapshotKeyCacherWithOptions(options SnapshotKeyCacherOptions) StatsKeyCacher {
+	if options.Clock == nil {
+		options.Clock = systemClock{}
+	}
+	return &snapshotKeyCacher{maxKeyAge: options.MaxKeyAge, clock: options.Clock}
+}
+
+// Get obtains a key from the current key set, and checks if the set is expired.
+func (skc *snapshotKeyCacher) Get(keyID string) (*jose.JSONWebKey, error) {
+	snapshot, _ := skc.snapshot.Load().(*keySnapshot)
+	if snapshot == nil {
+		atomic.AddUint64(&skc.misses, 1)
+		return nil, ErrNoKeyFound
+	}
+
+	key, err := snapshot.get(keyID, skc.maxKeyAge, skc.clock.Now())
+	if err != nil {
+		atomic.AddUint64(&skc.misses, 1)
+		return nil, err
+	}
+	atomic.AddUint64(&skc.hits, 1)
+	return key, nil
+}
+
+// Add replaces the key set with downloadedKeys, unless it holds the
+// same keys, and returns the key identified by keyID. The set is
+// replaced even when it lacks this key. A set holding the same keys
+// keeps its fetch time, which only AddKeySet updates.
+func (skc *snapshotKeyCacher) Add(keyID string, downloadedKeys []jose.JSONWebKey) (*jose.JSONWebKey, error) {
+	snapshot, _ := skc.snapshot.Load().(*keySnapshot)
+	if snapshot == nil || !sameKeys(snapshot.set.Keys, downloadedKeys) {
+		snapshot = newKeySnapshot(KeySet{Keys: downloadedKeys, FetchedAt: skc.clock.Now()})
+		skc.snapshot.Store(snapshot)
+	}
+
+	for _, key := range downloadedKeys {
+		if key.KeyID == keyID {
+			return &key, nil
+		}
+	}
+	return nil, ErrNoKeyFound
+}
+
+// AddKeySet implements the KeySetCacher interface.
+// The set expires maxKeyAge after its FetchedAt time.
+func (skc *snapshotKeyCacher) AddKeySet(set KeySet) {
+	skc.snapshot.Store(newKeySnapshot(set))
+}
+
+// LastKeySet implements the KeySetCacher interface.
+func (skc *snapshotKeyCacher) LastKeySet() (KeySet, bool) {
+	snapshot, _ := skc.snapshot.Load().(*keySnapshot)
+	if snapshot == nil {
+		return KeySet{}, false
+	}
+	return snapshot.set, true
+}
+
+// Stats implements the StatsKeyCacher interface.
+// Keys are never evicted from the set, they are replaced with it.
+func (skc *snapshotKeyCacher) Stats() KeyCacherStats {
+	return KeyCacherStats{
+		Hits:   atomic.LoadUint64(&skc.hits),
+		Misses: atomic.LoadUint64(&skc.misses),
+	}
+}
@@ -0,0 +1,180 @@
+package auth0
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestSnapshotKeyCacher(t *testing.T) {
+	downloadedKeys := []jose.JSONWebKey{
+		{Key: []byte("secret"), KeyID: "test1"},
+		{Key: []byte("secret"), KeyID: "test2"},
+	}
+
+	skc := NewSnapshotKeyCacher(time.Minute)
+	_, err := skc.Get("test1")
+	assert.Equal(t, ErrNoKeyFound, err)
+
+	key, err := skc.Add("test1", downloadedKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", key.KeyID)
+
+	key, err = skc.Get("test2")
+	assert.NoError(t, err, "the sibling key should be found without being added")
+	assert.Equal(t, "test2", key.KeyID)
+
+	// test1 is revoked
+	_, err = skc.Add("test3", []jose.JSONWebKey{downloadedKeys[1]})
+	assert.Equal(t, ErrNoKeyFound, err)
+	_, err = skc.Get("test1")
+	assert.Equal(t, ErrNoKeyFound, err, "the revoked key should be removed with the set")
+	_, err = skc.Get("test2")
+	assert.NoError(t, err)
+
+	assert.Equal(t, KeyCacherStats{Hits: 2, Misses: 2}, skc.Stats())
+}
+
+func TestSnapshotKeyCacherReusedKeyID(t *testing.T) {
+	clock := newFakeClock()
+	skc := NewSnapshotKeyCacherWithOptions(SnapshotKeyCacherOptions{MaxKeyAge: time.Minute, Clock: clock})
+	_, err := skc.Add("test1", []jose.JSONWebKey{{Key: []byte("old"), KeyID: "test1"}})
+	assert.NoError(t, err)
+
+	clock.Advance(time.Second)
+	key, err := skc.Add("test1", []jose.JSONWebKey{{Key: []byte("new"), KeyID: "test1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), key.Key)
+	key, err = skc.Get("test1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), key.Key, "the key reusing the key ID should replace the set")
+	set, _ := skc.(KeySetCacher).LastKeySet()
+	assert.Equal(t, clock.Now(), set.FetchedAt)
+}
+
+func TestSnapshotKeyCacherExpiry(t *testing.T) {
+	downloadedKeys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}}
+
+	tests := []struct {
+		name          string
+		maxKeyAge     time.Duration
+		expectedError error
+	}{
+		{name: "not expired", maxKeyAge: time.Minute},
+		{name: "never expires", maxKeyAge: MaxKeyAgeNoCheck},
+		{name: "expired", maxKeyAge: 0, expectedError: ErrKeyExpired},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := newFakeClock()
+			skc := NewSnapshotKeyCacherWithOptions(SnapshotKeyCacherOptions{MaxKeyAge: test.maxKeyAge, Clock: clock})
+			skc.Add("test1", downloadedKeys)
+			clock.Advance(time.Second)
+
+			_, err := skc.Get("test1")
+			assert.Equal(t, test.expectedError, err)
+		})
+	}
+}
+
+func TestSnapshotKeyCacherKeySet(t *testing.T) {
+	clock := newFakeClock()
+	skc := NewSnapshotKeyCacherWithOptions(SnapshotKeyCacherOptions{MaxKeyAge: time.Minute, Clock: clock})
+	keySetCacher, ok := skc.(KeySetCacher)
+	assert.True(t, ok)
+
+	_, ok = keySetCacher.LastKeySet()
+	assert.False(t, ok)
+
+	set := KeySet{
+		Keys:      []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}},
+		FetchedAt: clock.Now().Add(-50 * time.Second),
+		ETag:      `"v1"`,
+	}
+	keySetCacher.AddKeySet(set)
+	lastSet, ok := keySetCacher.LastKeySet()
+	assert.True(t, ok)
+	assert.Equal(t, set, lastSet)
+
+	_, err := skc.Get("test1")
+	assert.NoError(t, err)
+
+	// the last downloaded keys handed again should not extend their age
+	_, err = skc.Add("test1", set.Keys)
+	assert.NoError(t, err)
+	clock.Advance(20 * time.Second)
+	_, err = skc.Get("test1")
+	assert.Equal(t, ErrKeyExpired, err, "the set should expire a minute after being fetched")
+}
+
+func TestSnapshotKeyCacherConcurrentGetAdd(t *testing.T) {
+	sets := make([][]jose.JSONWebKey, 4)
+	for i := range sets {
+		for j := 0; j <= i; j++ {
+			sets[i] = append(sets[i], jose.JSONWebKey{Key: []byte("secret"), KeyID: "key" + strconv.Itoa(j)})
+		}
+	}
+
+	skc := NewSnapshotKeyCacher(time.Minute)
+	var wg sync.WaitGroup
+	for g := 0; g < 32; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				if i%10 == 0 {
+					skc.Add("key0", sets[(g+i)%len(sets)])
+					continue
+				}
+				key, err := skc.Get("key" + strconv.Itoa(i%len(sets)))
+				if err == nil && key.KeyID != "key"+strconv.Itoa(i%len(sets)) {
+					t.Errorf("Getting key%d returned %s", i%len(sets), key.KeyID)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestJWKClientWithSnapshotKeyCacher(t *testing.T) {
+	jsonWebKeyRS256 := genRSASSAJWK(jose.RS256, "keyRS256")
+	jsonWebKeyES384 := genECDSAJWK(jose.ES384, "keyES384")
+	var revoked int32
+	ts, counter := genJWKSTestServer(t, func(call uint64, w http.ResponseWriter, r *http.Request) bool {
+		if atomic.LoadInt32(&revoked) == 0 {
+			return false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(JWKS{Keys: []jose.JSONWebKey{jsonWebKeyES384.Public()}})
+		return true
+	}, jsonWebKeyRS256.Public(), jsonWebKeyES384.Public())
+	defer ts.Close()
+
+	clock := newFakeClock()
+	keyCacher := NewSnapshotKeyCacherWithOptions(SnapshotKeyCacherOptions{MaxKeyAge: time.Minute, Clock: clock})
+	client := NewJWKClientWithCache(JWKClientOptions{URI: ts.URL, Clock: clock}, nil, keyCacher)
+	_, err := client.GetKey("keyRS256")
+	assert.NoError(t, err)
+	_, err = client.GetKey("keyES384")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(counter), "sibling keys should not be downloaded again")
+
+	atomic.StoreInt32(&revoked, 1)
+	clock.Advance(DefaultMinDownloadInterval)
+	_, err = client.GetKey("unknown")
+	assert.Error(t, err)
+	assert.Equal(t, uint64(2), atomic.LoadUint64(counter))
+
+	_, err = client.GetKey("keyRS256")
+	assert.Error(t, err, "the revoked key should not be found once the set is replaced")
+	_, err = client.GetKey("keyES384")
+	assert.NoError(t, err)
+}
@@ -3,6 +3,7 @@ package auth0
 import (
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,6 +12,14 @@ import (
 	"gopkg.in/square/go-jose.v2"
 )
 
+func newTestMemoryKeyCacher(maxKeyAge time.Duration, maxCacheSize int) *memoryKeyCacher {
+	return NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{
+		MaxKeyAge:    maxKeyAge,
+		MaxCacheSize: maxCacheSize,
+		Clock:        newFakeClock(),
+	}).(*memoryKeyCacher)
+}
+
 func TestGet(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -19,42 +28,26 @@ func TestGet(t *testing.T) {
 		expectedErrorMsg string
 	}{
 		{
-			name: "pass - persistent cacher",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    MaxKeyAgeNoCheck,
-				maxCacheSize: MaxCacheSizeNoCheck,
-			},
+			name:             "pass - persistent cacher",
+			mkc:              newTestMemoryKeyCacher(MaxKeyAgeNoCheck, MaxCacheSizeNoCheck),
 			key:              "key1",
 			expectedErrorMsg: "",
 		},
 		{
-			name: "fail - invalid key",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    MaxKeyAgeNoCheck,
-				maxCacheSize: MaxCacheSizeNoCheck,
-			},
+			name:             "fail - invalid key",
+			mkc:              newTestMemoryKeyCacher(MaxKeyAgeNoCheck, MaxCacheSizeNoCheck),
 			key:              "invalid key",
 			expectedErrorMsg: "no Keys has been found",
 		},
 		{
-			name: "fail - persistent cacher get immediately expired key",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(0),
-				maxCacheSize: MaxCacheSizeNoCheck,
-			},
+			name:             "fail - persistent cacher get immediately expired key",
+			mkc:              newTestMemoryKeyCacher(time.Duration(0), MaxCacheSizeNoCheck),
 			key:              "key1",
 			expectedErrorMsg: "key exists but is expired",
 		},
 		{
-			name: "pass - persistent cacher get not expired key",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(10) * time.Second,
-				maxCacheSize: MaxCacheSizeNoCheck,
-			},
+			name:             "pass - persistent cacher get not expired key",
+			mkc:              newTestMemoryKeyCacher(time.Duration(10)*time.Second, MaxCacheSizeNoCheck),
 			key:              "key1",
 			expectedErrorMsg: "",
 		},
@@ -89,51 +82,38 @@ func TestGet(t *testing.T) {
 			expectedErrorMsg: "no Keys has been found",
 		},
 		{
-			name: "pass - custom cacher with -1 max age",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    MaxKeyAgeNoCheck,
-				maxCacheSize: 1,
-			},
+			name:             "pass - custom cacher with -1 max age",
+			mkc:              newTestMemoryKeyCacher(MaxKeyAgeNoCheck, 1),
 			key:              "key1",
 			expectedErrorMsg: "",
 		},
 		{
-			name: "fail - custom cacher get immediately expired key",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(0),
-				maxCacheSize: 1,
-			},
+			name:             "fail - custom cacher get immediately expired key",
+			mkc:              newTestMemoryKeyCacher(time.Duration(0), 1),
 			key:              "key1",
 			expectedErrorMsg: "key exists but is expired",
 		},
 		{
-			name: "pass - custom cacher not expired",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(100) * time.Second,
-				maxCacheSize: 1,
-			},
+			name:             "pass - custom cacher not expired",
+			mkc:              newTestMemoryKeyCacher(time.Duration(100)*time.Second, 1),
 			key:              "key1",
 			expectedErrorMsg: "",
 		},
 		{
-			name: "fail - custom cacher with expired key",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(-100) * time.Second, // setting max age negavtive time duration is equivalent to expired keys
-				maxCacheSize: 1,
-			},
+			name:             "fail - custom cacher with expired key",
+			mkc:              newTestMemoryKeyCacher(time.Duration(-100)*time.Second, 1), // setting max age negavtive time duration is equivalent to expired keys
 			key:              "key1",
 			expectedErrorMsg: "key exists but is expired",
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			clock := newFakeClock()
+			test.mkc.clock = clock
 			if test.mkc.entries != nil {
-				test.mkc.entries["key1"] = keyCacherEntry{time.Now(), jose.JSONWebKey{KeyID: "test1"}}
+				test.mkc.set("key1", keyCacherEntry{clock.Now(), jose.JSONWebKey{KeyID: "test1"}})
 			}
+			clock.Advance(time.Second)
 
 			_, err := test.mkc.Get(test.key)
 
@@ -168,154 +148,102 @@ func TestAdd(t *testing.T) {
 		expectedErrorMsg string
 	}{
 		{
-			name: "pass - persistent cacher",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    MaxKeyAgeNoCheck,
-				maxCacheSize: MaxCacheSizeNoCheck,
-			},
+			name:             "pass - persistent cacher",
+			mkc:              newTestMemoryKeyCacher(MaxKeyAgeNoCheck, MaxCacheSizeNoCheck),
 			addingKey:        "test1",
 			gettingKey:       "test1",
 			expectedFoundKey: true,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "fail - invalid key",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    MaxKeyAgeNoCheck,
-				maxCacheSize: MaxCacheSizeNoCheck,
-			},
+			name:             "fail - invalid key",
+			mkc:              newTestMemoryKeyCacher(MaxKeyAgeNoCheck, MaxCacheSizeNoCheck),
 			addingKey:        "invalid key",
 			gettingKey:       "invalid key",
 			expectedFoundKey: false,
 			expectedErrorMsg: "no Keys has been found",
 		},
 		{
-			name: "pass - add key for persistent cacher",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(0),
-				maxCacheSize: MaxCacheSizeNoCheck,
-			},
+			name:             "pass - add key for persistent cacher",
+			mkc:              newTestMemoryKeyCacher(time.Duration(0), MaxCacheSizeNoCheck),
 			addingKey:        "test1",
 			gettingKey:       "test1",
 			expectedFoundKey: true,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "pass - add key for persistent cacher",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(10) * time.Second,
-				maxCacheSize: MaxCacheSizeNoCheck,
-			},
+			name:             "pass - add key for persistent cacher",
+			mkc:              newTestMemoryKeyCacher(time.Duration(10)*time.Second, MaxCacheSizeNoCheck),
 			addingKey:        "test1",
 			gettingKey:       "test1",
 			expectedFoundKey: true,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "fail - no cacher with -1 max age",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    MaxKeyAgeNoCheck,
-				maxCacheSize: 0,
-			},
+			name:             "fail - no cacher with -1 max age",
+			mkc:              newTestMemoryKeyCacher(MaxKeyAgeNoCheck, 0),
 			addingKey:        "test1",
 			gettingKey:       "test1",
 			expectedFoundKey: false,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "fail - no cacher",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(0),
-				maxCacheSize: 0,
-			},
+			name:             "fail - no cacher",
+			mkc:              newTestMemoryKeyCacher(time.Duration(0), 0),
 			addingKey:        "test1",
 			gettingKey:       "test1",
 			expectedFoundKey: false,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "fail - no cacher with 10sec max age",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(10) * time.Second,
-				maxCacheSize: 0,
-			},
+			name:             "fail - no cacher with 10sec max age",
+			mkc:              newTestMemoryKeyCacher(time.Duration(10)*time.Second, 0),
 			addingKey:        "test1",
 			gettingKey:       "test1",
 			expectedFoundKey: false,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "pass - custom cacher with -1 max age",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    MaxKeyAgeNoCheck,
-				maxCacheSize: 1,
-			},
+			name:             "pass - custom cacher with -1 max age",
+			mkc:              newTestMemoryKeyCacher(MaxKeyAgeNoCheck, 1),
 			addingKey:        "test1",
 			gettingKey:       "test1",
 			expectedFoundKey: true,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "pass - custom cacher with 0 max age",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(0),
-				maxCacheSize: 1,
-			},
+			name:             "pass - custom cacher with 0 max age",
+			mkc:              newTestMemoryKeyCacher(time.Duration(0), 1),
 			addingKey:        "test1",
 			gettingKey:       "test1",
 			expectedFoundKey: true,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "pass - custom cacher get latest added key",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(100) * time.Second,
-				maxCacheSize: 1,
-			},
+			name:             "pass - custom cacher get latest added key",
+			mkc:              newTestMemoryKeyCacher(time.Duration(100)*time.Second, 1),
 			gettingKey:       "test3",
 			expectedFoundKey: true,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "fail - custom cacher add invalid key",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(100) * time.Second,
-				maxCacheSize: 1,
-			},
+			name:             "fail - custom cacher add invalid key",
+			mkc:              newTestMemoryKeyCacher(time.Duration(100)*time.Second, 1),
 			addingKey:        "invalid key",
 			gettingKey:       "test1",
 			expectedFoundKey: false,
 			expectedErrorMsg: "no Keys has been found",
 		},
 		{
-			name: "fail - custom cacher get key not in cache",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(100) * time.Second,
-				maxCacheSize: 1,
-			},
+			name:             "fail - custom cacher get key not in cache",
+			mkc:              newTestMemoryKeyCacher(time.Duration(100)*time.Second, 1),
 			gettingKey:       "test1",
 			expectedFoundKey: false,
 			expectedErrorMsg: "",
 		},
 		{
-			name: "pass - custom cacher with capacity 3",
-			mkc: &memoryKeyCacher{
-				entries:      make(map[string]keyCacherEntry),
-				maxKeyAge:    time.Duration(100) * time.Second,
-				maxCacheSize: 3,
-			},
+			name:             "pass - custom cacher with capacity 3",
+			mkc:              newTestMemoryKeyCacher(time.Duration(100)*time.Second, 3),
 			gettingKey:       "test2",
 			expectedFoundKey: true,
 			expectedErrorMsg: "",
@@ -357,31 +285,20 @@ func TestKeyIsExpired(t *testing.T) {
 		expectedBool bool
 	}{
 		{
-			name: "true - key is expired",
-			mkc: &memoryKeyCacher{
-				entries:      map[string]keyCacherEntry{},
-				maxKeyAge:    time.Duration(1) * time.Second,
-				maxCacheSize: 1,
-			},
+			name:         "true - key is expired",
+			mkc:          newTestMemoryKeyCacher(time.Duration(1)*time.Second, 1),
 			expectedBool: true,
 		},
 		{
-			name: "false - key not expired",
-			mkc: &memoryKeyCacher{
-				entries:      map[string]keyCacherEntry{},
-				maxKeyAge:    time.Duration(10) * time.Second,
-				maxCacheSize: 1,
-			},
+			name:         "false - key not expired",
+			mkc:          newTestMemoryKeyCacher(time.Duration(10)*time.Second, 1),
 			expectedBool: false,
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			if test.expectedBool {
-				test.mkc.entries["test1"] = keyCacherEntry{time.Now().Add(time.Duration(-10) * time.Second), jose.JSONWebKey{KeyID: "test1"}}
-			} else {
-				test.mkc.entries["test1"] = keyCacherEntry{time.Now(), jose.JSONWebKey{KeyID: "test1"}}
-			}
+			test.mkc.set("test1", keyCacherEntry{test.mkc.clock.Now(), jose.JSONWebKey{KeyID: "test1"}})
+			test.mkc.clock.(*fakeClock).Advance(time.Duration(5) * time.Second)
 			if test.mkc.keyIsExpired("test1") != test.expectedBool {
 				t.Errorf("Should have been " + strconv.FormatBool(test.expectedBool) + " but got different")
 			}
@@ -398,28 +315,25 @@ func TestHandleOverflow(t *testing.T) {
 		expectedLength int
 	}{
 		{
-			name: "true - overflowed and delete 1 key",
-			mkc: &memoryKeyCacher{
-				entries:      map[string]keyCacherEntry{},
-				maxKeyAge:    time.Duration(2) * time.Second,
-				maxCacheSize: 1,
-			},
+			name:           "true - overflowed and delete 1 key",
+			mkc:            newTestMemoryKeyCacher(time.Duration(2)*time.Second, 1),
 			expectedLength: 1,
 		},
 		{
-			name: "false - no overflow",
-			mkc: &memoryKeyCacher{
-				entries:      map[string]keyCacherEntry{},
-				maxKeyAge:    time.Duration(2) * time.Second,
-				maxCacheSize: 2,
-			},
+			name:           "false - no overflow",
+			mkc:            newTestMemoryKeyCacher(time.Duration(2)*time.Second, 2),
+			expectedLength: 2,
+		},
+		{
+			name:           "false - negative size other than MaxCacheSizeNoCheck",
+			mkc:            newTestMemoryKeyCacher(time.Duration(2)*time.Second, -2),
 			expectedLength: 2,
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			test.mkc.entries["first"] = keyCacherEntry{JSONWebKey: downloadedKeys[0]}
-			test.mkc.entries["second"] = keyCacherEntry{JSONWebKey: downloadedKeys[1]}
+			test.mkc.set("first", keyCacherEntry{JSONWebKey: downloadedKeys[0]})
+			test.mkc.set("second", keyCacherEntry{JSONWebKey: downloadedKeys[1]})
 			test.mkc.handleOverflow()
 			if len(test.mkc.entries) != test.expectedLength {
 				t.Errorf("Should have been " + strconv.Itoa(test.expectedLength) + "but got different")
@@ -427,3 +341,226 @@ func TestHandleOverflow(t *testing.T) {
 		})
 	}
 }
+
+func TestConcurrentGetAdd(t *testing.T) {
+	downloadedKeys := make([]jose.JSONWebKey, 16)
+	for i := range downloadedKeys {
+		downloadedKeys[i] = jose.JSONWebKey{Key: []byte("secret"), KeyID: "key" + strconv.Itoa(i)}
+	}
+
+	tests := []struct {
+		name string
+		mkc  KeyCacher
+	}{
+		{name: "persistent cacher", mkc: newMemoryPersistentKeyCacher()},
+		{name: "expiring cacher", mkc: NewMemoryKeyCacher(time.Millisecond, MaxCacheSizeNoCheck)},
+		{name: "bounded cacher", mkc: NewMemoryKeyCacher(time.Minute, 4)},
+		{name: "expiring bounded cacher", mkc: NewMemoryKeyCacher(time.Millisecond, 4)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for g := 0; g < 32; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 500; i++ {
+						keyID := downloadedKeys[(g+i)%len(downloadedKeys)].KeyID
+						if i%4 == 0 {
+							key, err := test.mkc.Add(keyID, downloadedKeys)
+							if err != nil || key.KeyID != keyID {
+								t.Errorf("Adding %s should have succeeded, but got: %v", keyID, err)
+								return
+							}
+							continue
+						}
+						key, err := test.mkc.Get(keyID)
+						if err == nil && key.KeyID != keyID {
+							t.Errorf("Getting %s returned %s", keyID, key.KeyID)
+							return
+						}
+						if err != nil && err != ErrNoKeyFound && err != ErrKeyExpired {
+							t.Errorf("Unexpected error getting %s: %v", keyID, err)
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestConcurrentGetExpiredKey(t *testing.T) {
+	clock := newFakeClock()
+	mkc := NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{MaxKeyAge: time.Minute, MaxCacheSize: MaxCacheSizeNoCheck, Clock: clock})
+	downloadedKeys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "key"}}
+	if _, err := mkc.Add("key", downloadedKeys); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Minute)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 32; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := mkc.Get("key")
+			if err != ErrKeyExpired && err != ErrNoKeyFound {
+				t.Errorf("Getting an expired key should have failed, but got: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, err := mkc.Get("key")
+	assert.Equal(t, ErrNoKeyFound, err, "the expired key should have been deleted")
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	downloadedKeys := []jose.JSONWebKey{
+		{Key: []byte("secret"), KeyID: "test1"},
+		{Key: []byte("secret"), KeyID: "test2"},
+		{Key: []byte("secret"), KeyID: "test3"},
+	}
+
+	tests := []struct {
+		name        string
+		policy      EvictionPolicy
+		evictedKey  string
+		expectedKey string
+	}{
+		{name: "least recently used", policy: EvictLeastRecentlyUsed, evictedKey: "test2", expectedKey: "test1"},
+		{name: "oldest", policy: EvictOldest, evictedKey: "test1", expectedKey: "test2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mkc := NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{
+				MaxKeyAge:      time.Minute,
+				MaxCacheSize:   2,
+				EvictionPolicy: test.policy,
+			})
+			mkc.Add("test1", downloadedKeys)
+			mkc.Add("test2", downloadedKeys)
+			// test1 is used again but added first
+			_, err := mkc.Get("test1")
+			assert.NoError(t, err)
+			mkc.Add("test3", downloadedKeys)
+
+			_, err = mkc.Get(test.evictedKey)
+			assert.Equal(t, ErrNoKeyFound, err)
+			_, err = mkc.Get(test.expectedKey)
+			assert.NoError(t, err)
+			_, err = mkc.Get("test3")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestKeyCacherStats(t *testing.T) {
+	downloadedKeys := []jose.JSONWebKey{
+		{Key: []byte("secret"), KeyID: "test1"},
+		{Key: []byte("secret"), KeyID: "test2"},
+	}
+
+	mkc := NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{MaxKeyAge: time.Minute, MaxCacheSize: 1})
+	mkc.Get("test1")
+	mkc.Add("test1", downloadedKeys)
+	mkc.Get("test1")
+	mkc.Get("test1")
+	mkc.Add("test2", downloadedKeys)
+	mkc.Get("test1")
+
+	assert.Equal(t, KeyCacherStats{Hits: 2, Misses: 2, Evictions: 1}, mkc.Stats())
+
+	clock := newFakeClock()
+	expiring := NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{MaxKeyAge: time.Minute, MaxCacheSize: MaxCacheSizeNoCheck, Clock: clock})
+	expiring.Add("test1", downloadedKeys)
+	clock.Advance(2 * time.Minute)
+	_, err := expiring.Get("test1")
+	assert.Equal(t, ErrKeyExpired, err)
+	assert.Equal(t, KeyCacherStats{Misses: 1}, expiring.Stats())
+}
+
+func TestGracePeriod(t *testing.T) {
+	mkc := NewMemoryKeyCacherWithOptions(MemoryKeyCacherOptions{
+		MaxKeyAge:    time.Minute,
+		MaxCacheSize: MaxCacheSizeNoCheck,
+		GracePeriod:  time.Hour,
+		Clock:        newFakeClock(),
+	}).(*memoryKeyCacher)
+	now := mkc.clock.Now()
+
+	tests := []struct {
+		name          string
+		addedAt       time.Time
+		expectedError error
+		expectedStale error
+		expectedKept  bool
+	}{
+		{name: "not expired", addedAt: now, expectedKept: true},
+		{name: "in grace period", addedAt: now.Add(-30 * time.Minute), expectedError: ErrKeyExpired, expectedKept: true},
+		{name: "grace period over", addedAt: now.Add(-2 * time.Hour), expectedError: ErrKeyExpired, expectedStale: ErrKeyExpired},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mkc.set("test1", keyCacherEntry{test.addedAt, jose.JSONWebKey{KeyID: "test1"}})
+
+			key, err := mkc.GetStale("test1")
+			assert.Equal(t, test.expectedStale, err)
+			if err == nil {
+				assert.Equal(t, "test1", key.KeyID)
+			}
+
+			_, err = mkc.Get("test1")
+			assert.Equal(t, test.expectedError, err)
+			_, kept := mkc.entries["test1"]
+			assert.Equal(t, test.expectedKept, kept)
+		})
+	}
+
+	_, err := mkc.GetStale("unknown")
+	assert.Equal(t, ErrNoKeyFound, err)
+	assert.Equal(t, KeyCacherStats{Hits: 2, Misses: 2, StaleHits: 1}, mkc.Stats())
+}
+
+func TestHandleOverflowEvictsBack(t *testing.T) {
+	mkc := newTestMemoryKeyCacher(time.Minute, 100)
+	for i := 0; i < 1000; i++ {
+		keyID := strconv.Itoa(i)
+		mkc.set(keyID, keyCacherEntry{mkc.clock.Now(), jose.JSONWebKey{KeyID: keyID}})
+		mkc.handleOverflow()
+	}
+
+	assert.Len(t, mkc.entries, 100)
+	assert.Equal(t, 100, mkc.order.Len())
+	assert.Equal(t, uint64(900), mkc.Stats().Evictions)
+	for i := 900; i < 1000; i++ {
+		_, ok := mkc.entries[strconv.Itoa(i)]
+		assert.True(t, ok, "the most recent keys should be kept")
+	}
+}
+
+func TestAddNegativeCacheSize(t *testing.T) {
+	mkc := NewMemoryKeyCacher(time.Minute, -2)
+	downloadedKeys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "test1"}, {Key: []byte("secret"), KeyID: "test2"}}
+
+	key, err := mkc.Add("test1", downloadedKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", key.KeyID)
+	_, err = mkc.Get("test2")
+	assert.NoError(t, err, "a negative size should keep every downloaded key")
+}
+
+func BenchmarkAddOverflow(b *testing.B) {
+	mkc := NewMemoryKeyCacher(time.Minute, 1000)
+	keys := make([][]jose.JSONWebKey, b.N)
+	for i := range keys {
+		keys[i] = []jose.JSONWebKey{{Key: []byte("secret"), KeyID: strconv.Itoa(i)}}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mkc.Add(keys[i][0].KeyID, keys[i])
+	}
+}
@@ -0,0 +1,135 @@
+package auth0
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+type contextKey int
+
+const (
+	tokenContextKey contextKey = iota
+	claimsContextKey
+	customClaimsContextKey
+)
+
+// ErrorHandler is called by the middleware when
+// the request could not be validated.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultErrorHandler responds with a 403 Forbidden status when the token
+// does not fulfill the requirements, and a 401 Unauthorized status otherwise,
+// along with a WWW-Authenticate header without realm as WriteBearerError does.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	WriteBearerError(w, "", err)
+}
+
+// MiddlewareOptions configures the behaviour of the
+// middleware returned by MiddlewareWithOptions.
+type MiddlewareOptions struct {
+	// ErrorHandler is called when validation fails.
+	// Defaults to DefaultErrorHandler.
+	ErrorHandler ErrorHandler
+	// CredentialsOptional lets requests without any token
+	// through to the next handler. Requests carrying an
+	// invalid token are still rejected.
+	CredentialsOptional bool
+	// Requirements must all be fulfilled by the access claims of the
+	// token. Failures are reported to ErrorHandler as an AuthorizationError.
+	Requirements []Requirement
+}
+
+// Middleware validates the token of incoming requests before
+// calling next. The token and its claims are stored in the
+// request context and can be read with TokenFromContext,
+// ClaimsFromContext, Auth0ClaimsFromContext and CustomClaimsFromContext.
+func (v *JWTValidator) Middleware(next http.Handler) http.Handler {
+	return v.MiddlewareWithOptions(next, MiddlewareOptions{})
+}
+
+// MiddlewareWithOptions is like Middleware but with
+// a configurable behaviour.
+func (v *JWTValidator) MiddlewareWithOptions(next http.Handler, options MiddlewareOptions) http.Handler {
+	return newMiddleware(v, next, options)
+}
+
+// requestValidator is implemented by the
+// validators the middleware can be built on.
+type requestValidator interface {
+	ValidateRequestClaims(r *http.Request, values ...interface{}) (*jwt.JSONWebToken, error)
+}
+
+func newMiddleware(v requestValidator, next http.Handler, options MiddlewareOptions) http.Handler {
+	if options.ErrorHandler == nil {
+		options.ErrorHandler = DefaultErrorHandler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := &Auth0Claims{}
+		customClaims := map[string]interface{}{}
+		token, err := v.ValidateRequestClaims(r, claims, &customClaims)
+		if errors.Is(err, ErrTokenNotFound) && options.CredentialsOptional {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			options.ErrorHandler(w, r, err)
+			return
+		}
+
+		if err := Authorize(claims.AccessClaims, options.Requirements...); err != nil {
+			options.ErrorHandler(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tokenContextKey, token)
+		ctx = context.WithValue(ctx, claimsContextKey, claims)
+		ctx = context.WithValue(ctx, customClaimsContextKey, customClaims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// TokenFromContext returns the validated token stored
+// in the context by the middleware.
+func TokenFromContext(ctx context.Context) (*jwt.JSONWebToken, bool) {
+	token, ok := ctx.Value(tokenContextKey).(*jwt.JSONWebToken)
+	return token, ok
+}
+
+// ClaimsFromContext returns the registered claims of the
+// validated token stored in the context by the middleware.
+func ClaimsFromContext(ctx context.Context) (jwt.Claims, bool) {
+	claims, ok := Auth0ClaimsFromContext(ctx)
+	if !ok {
+		return jwt.Claims{}, false
+	}
+	return claims.Claims, true
+}
+
+// Auth0ClaimsFromContext returns the Auth0 claims of the validated
+// token stored in the context by the middleware.
+func Auth0ClaimsFromContext(ctx context.Context) (*Auth0Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*Auth0Claims)
+	return claims, ok
+}
+
+// CustomClaimsFromContext returns all the claims of the validated
+// token stored in the context by the middleware.
+func CustomClaimsFromContext(ctx context.Context) (map[string]interface{}, bool) {
+	claims, ok := ctx.Value(customClaimsContextKey).(map[string]interface{})
+	return claims, ok
+}
+
+// DecodeClaimsFromContext unmarshalls the claims of the token stored
+// in the context by the middleware into values. The signature has
+// already been verified by the middleware and is not checked again.
+func DecodeClaimsFromContext(ctx context.Context, values ...interface{}) error {
+	token, ok := TokenFromContext(ctx)
+	if !ok {
+		return ErrTokenNotFound
+	}
+	return token.UnsafeClaimsWithoutVerification(values...)
+}
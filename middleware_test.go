@@ -0,0 +1,135 @@
+package auth0
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestMiddleware(t *testing.T) {
+	configuration := NewConfiguration(defaultSecretProvider, defaultAudience, defaultIssuer, jose.HS256)
+
+	tests := []struct {
+		name               string
+		token              string
+		options            MiddlewareOptions
+		expectedStatusCode int
+		expectedNextCalled bool
+	}{
+		{
+			name:               "pass - valid token",
+			token:              getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+			expectedStatusCode: http.StatusOK,
+			expectedNextCalled: true,
+		},
+		{
+			name:               "fail - expired token",
+			token:              getTestToken(defaultAudience, defaultIssuer, time.Now().Add(-24*time.Hour), jose.HS256, defaultSecret),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedNextCalled: false,
+		},
+		{
+			name:               "fail - no token",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedNextCalled: false,
+		},
+		{
+			name:               "pass - no token with optional credentials",
+			options:            MiddlewareOptions{CredentialsOptional: true},
+			expectedStatusCode: http.StatusOK,
+			expectedNextCalled: true,
+		},
+		{
+			name:               "fail - invalid token with optional credentials",
+			token:              getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.HS256, []byte("invalid secret")),
+			options:            MiddlewareOptions{CredentialsOptional: true},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedNextCalled: false,
+		},
+		{
+			name:  "fail - custom error handler",
+			token: getTestToken(defaultAudience, "invalid iss", time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+			options: MiddlewareOptions{
+				ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+					w.WriteHeader(http.StatusTeapot)
+				},
+			},
+			expectedStatusCode: http.StatusTeapot,
+			expectedNextCalled: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator := NewValidator(configuration, nil)
+			nextCalled := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+			})
+
+			req := httptest.NewRequest("GET", "http://localhost", nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			rec := httptest.NewRecorder()
+			validator.MiddlewareWithOptions(next, test.options).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedStatusCode, rec.Code)
+			assert.Equal(t, test.expectedNextCalled, nextCalled)
+		})
+	}
+}
+
+func TestMiddlewareContext(t *testing.T) {
+	configuration := NewConfiguration(defaultSecretProvider, defaultAudience, defaultIssuer, jose.HS256)
+	validator := NewValidator(configuration, nil)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := TokenFromContext(r.Context())
+		assert.True(t, ok)
+		assert.NotNil(t, token)
+
+		claims, ok := ClaimsFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, defaultIssuer, claims.Issuer)
+		assert.Equal(t, jwt.Audience(defaultAudience), claims.Audience)
+
+		customClaims, ok := CustomClaimsFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, defaultIssuer, customClaims["iss"])
+
+		decoded := struct {
+			Issuer string `json:"iss"`
+		}{}
+		assert.NoError(t, DecodeClaimsFromContext(r.Context(), &decoded))
+		assert.Equal(t, defaultIssuer, decoded.Issuer)
+	})
+
+	token := getTestToken(defaultAudience, defaultIssuer, time.Now().Add(24*time.Hour), jose.HS256, defaultSecret)
+	req := httptest.NewRequest("GET", "http://localhost", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	validator.Middleware(next).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestContextAccessorsWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://localhost", nil)
+
+	_, ok := TokenFromContext(req.Context())
+	assert.False(t, ok)
+	_, ok = ClaimsFromContext(req.Context())
+	assert.False(t, ok)
+	_, ok = CustomClaimsFromContext(req.Context())
+	assert.False(t, ok)
+
+	err := DecodeClaimsFromContext(req.Context(), &map[string]interface{}{})
+	assert.True(t, errors.Is(err, ErrTokenNotFound))
+}
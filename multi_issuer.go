@@ -0,0 +1,161 @@
+package auth0
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	// ErrUnknownIssuer is returned when the issuer of the
+	// token is not one of the allowed issuers.
+	ErrUnknownIssuer = errors.New("issuer is not allowed")
+)
+
+// MultiIssuerValidator validates tokens issued by
+// several issuers, such as several Auth0 tenants.
+type MultiIssuerValidator struct {
+	validators map[string]*JWTValidator
+	extractor  RequestTokenExtractor
+}
+
+// NewMultiIssuerValidator creates a validator accepting only the
+// tokens issued by one of the keys of configurations.
+// Tokens are validated with the Configuration of their issuer,
+// which expected issuer is always set to its key.
+func NewMultiIssuerValidator(configurations map[string]Configuration, extractor RequestTokenExtractor) *MultiIssuerValidator {
+	if extractor == nil {
+		extractor = RequestTokenExtractorFunc(FromHeader)
+	}
+
+	validators := make(map[string]*JWTValidator, len(configurations))
+	for issuer, config := range configurations {
+		config.expectedClaims.Issuer = issuer
+		validators[issuer] = NewValidator(config, extractor)
+	}
+
+	return &MultiIssuerValidator{validators, extractor}
+}
+
+// NewMultiIssuerValidatorWithOptions creates a validator accepting only
+// the tokens issued by one of the keys of issuers, like
+// NewMultiIssuerValidator. The validator of each issuer is created by
+// NewValidatorWithOptions with options followed by the options of the
+// issuer, and its expected issuer is always set to its key.
+// The extractor set by WithExtractor in options extracts the tokens.
+func NewMultiIssuerValidatorWithOptions(issuers map[string][]Option, options ...Option) (*MultiIssuerValidator, error) {
+	extractor := newValidatorOptions(options).extractor
+	if extractor == nil {
+		extractor = RequestTokenExtractorFunc(FromHeader)
+	}
+
+	validators := make(map[string]*JWTValidator, len(issuers))
+	for issuer, issuerOptions := range issuers {
+		opts := append(append(append([]Option{}, options...), issuerOptions...), WithIssuer(issuer))
+		validator, err := NewValidatorWithOptions(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("issuer %s: %w", issuer, err)
+		}
+		validators[issuer] = validator
+	}
+
+	return &MultiIssuerValidator{validators, extractor}, nil
+}
+
+// ValidateRequest validates the token within the http request
+// with the configuration of its issuer.
+// The leeway of the validator of the issuer, one minute by default,
+// is used to compare time values.
+func (m *MultiIssuerValidator) ValidateRequest(r *http.Request) (*jwt.JSONWebToken, error) {
+	return m.ValidateRequestContext(r.Context(), r)
+}
+
+// ValidateRequestContext is like ValidateRequest but retrieving
+// the secret stops as soon as ctx is done.
+func (m *MultiIssuerValidator) ValidateRequestContext(ctx context.Context, r *http.Request) (*jwt.JSONWebToken, error) {
+	return m.validateRequest(ctx, r)
+}
+
+// ValidateRequestClaims validates the token within the http request and
+// unmarshalls its claims into values, verifying its signature only once.
+func (m *MultiIssuerValidator) ValidateRequestClaims(r *http.Request, values ...interface{}) (*jwt.JSONWebToken, error) {
+	return m.validateRequest(r.Context(), r, values...)
+}
+
+func (m *MultiIssuerValidator) validateRequest(ctx context.Context, r *http.Request, values ...interface{}) (*jwt.JSONWebToken, error) {
+	token, err := m.extractor.Extract(r)
+	if err != nil {
+		return nil, err
+	}
+
+	validator, err := m.validatorFor(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validator.ValidateTokenClaimsContext(ctx, token, values...); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// ValidateToken validates the token with the configuration of its issuer.
+func (m *MultiIssuerValidator) ValidateToken(token *jwt.JSONWebToken) error {
+	return m.ValidateTokenContext(context.Background(), token)
+}
+
+// ValidateTokenContext is like ValidateToken but retrieving
+// the secret stops as soon as ctx is done.
+func (m *MultiIssuerValidator) ValidateTokenContext(ctx context.Context, token *jwt.JSONWebToken) error {
+	validator, err := m.validatorFor(token)
+	if err != nil {
+		return err
+	}
+	return validator.ValidateTokenContext(ctx, token)
+}
+
+// Claims unmarshall the claims of the provided token
+func (m *MultiIssuerValidator) Claims(token *jwt.JSONWebToken, values ...interface{}) error {
+	return m.ClaimsContext(context.Background(), token, values...)
+}
+
+// ClaimsContext is like Claims but retrieving
+// the secret stops as soon as ctx is done.
+func (m *MultiIssuerValidator) ClaimsContext(ctx context.Context, token *jwt.JSONWebToken, values ...interface{}) error {
+	validator, err := m.validatorFor(token)
+	if err != nil {
+		return err
+	}
+	return validator.ClaimsContext(ctx, token, values...)
+}
+
+// Middleware validates the token of incoming requests
+// before calling next, like JWTValidator.Middleware.
+func (m *MultiIssuerValidator) Middleware(next http.Handler) http.Handler {
+	return m.MiddlewareWithOptions(next, MiddlewareOptions{})
+}
+
+// MiddlewareWithOptions is like Middleware but with
+// a configurable behaviour.
+func (m *MultiIssuerValidator) MiddlewareWithOptions(next http.Handler, options MiddlewareOptions) http.Handler {
+	return newMiddleware(m, next, options)
+}
+
+// validatorFor returns the validator of the issuer of the token,
+// read from its claims before verifying its signature.
+func (m *MultiIssuerValidator) validatorFor(token *jwt.JSONWebToken) (*JWTValidator, error) {
+	claims := jwt.Claims{}
+	if err := token.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, newValidationError(ReasonMalformed, err)
+	}
+
+	validator, ok := m.validators[claims.Issuer]
+	if !ok {
+		return nil, newValidationError(ReasonIssuer, ErrUnknownIssuer)
+	}
+	return validator, nil
+}
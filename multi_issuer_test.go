@@ -0,0 +1,172 @@
+package auth0
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func genMultiIssuerValidator() *MultiIssuerValidator {
+	return NewMultiIssuerValidator(map[string]Configuration{
+		"https://tenant-a.auth0.com/": NewConfiguration(defaultSecretProvider, defaultAudience, "", jose.HS256),
+		"https://tenant-b.auth0.com/": NewConfiguration(defaultSecretProviderES384, defaultAudience, "https://other.auth0.com/", jose.ES384),
+	}, nil)
+}
+
+func TestMultiIssuerValidateRequest(t *testing.T) {
+	tests := []struct {
+		name             string
+		token            string
+		expectedErrorMsg string
+	}{
+		{
+			name:  "pass - first issuer",
+			token: getTestToken(defaultAudience, "https://tenant-a.auth0.com/", time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+		},
+		{
+			name:  "pass - second issuer",
+			token: getTestToken(defaultAudience, "https://tenant-b.auth0.com/", time.Now().Add(24*time.Hour), jose.ES384, defaultSecretES384),
+		},
+		{
+			name:             "fail - unknown issuer",
+			token:            getTestToken(defaultAudience, "https://evil.auth0.com/", time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+			expectedErrorMsg: "issuer is not allowed",
+		},
+		{
+			name:             "fail - no issuer",
+			token:            getTestToken(defaultAudience, "", time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+			expectedErrorMsg: "issuer is not allowed",
+		},
+		{
+			name:             "fail - issuer with the configuration of another issuer",
+			token:            getTestToken(defaultAudience, "https://tenant-b.auth0.com/", time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+			expectedErrorMsg: "algorithm is invalid",
+		},
+		{
+			name:             "fail - invalid token aud",
+			token:            getTestToken([]string{"invalid aud"}, "https://tenant-a.auth0.com/", time.Now().Add(24*time.Hour), jose.HS256, defaultSecret),
+			expectedErrorMsg: "invalid audience claim (aud)",
+		},
+		{
+			name:             "fail - invalid token secret",
+			token:            getTestToken(defaultAudience, "https://tenant-a.auth0.com/", time.Now().Add(24*time.Hour), jose.HS256, []byte("invalid secret")),
+			expectedErrorMsg: "error in cryptographic primitive",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator := genMultiIssuerValidator()
+			req, _ := http.NewRequest("", "http://localhost", nil)
+			req.Header.Add("Authorization", "Bearer "+test.token)
+
+			token, err := validator.ValidateRequest(req)
+
+			if test.expectedErrorMsg != "" {
+				if err == nil {
+					t.Errorf("Validation should have failed with error with substring: " + test.expectedErrorMsg)
+				} else if !strings.Contains(err.Error(), test.expectedErrorMsg) {
+					t.Errorf("Validation should have failed with error with substring: " + test.expectedErrorMsg + ", but got: " + err.Error())
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Validation should not have failed with error, but got: " + err.Error())
+				}
+
+				claims := map[string]interface{}{}
+				err = validator.Claims(token, &claims)
+				if err != nil {
+					t.Errorf("Claims unmarshall should not have failed with error, but got: " + err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestMultiIssuerMiddleware(t *testing.T) {
+	validator := genMultiIssuerValidator()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "https://tenant-b.auth0.com/", claims.Issuer)
+	})
+
+	token := getTestToken(defaultAudience, "https://tenant-b.auth0.com/", time.Now().Add(24*time.Hour), jose.ES384, defaultSecretES384)
+	req := httptest.NewRequest("GET", "http://localhost", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	validator.Middleware(next).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	token = getTestToken(defaultAudience, "https://evil.auth0.com/", time.Now().Add(24*time.Hour), jose.HS256, defaultSecret)
+	req = httptest.NewRequest("GET", "http://localhost", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	validator.Middleware(next).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestNewMultiIssuerValidatorWithOptions(t *testing.T) {
+	clock := newFakeClock()
+	validator, err := NewMultiIssuerValidatorWithOptions(map[string][]Option{
+		"https://tenant-a.auth0.com/": {WithSecretProvider(defaultSecretProvider), WithAlgorithms(jose.HS256), WithHMAC()},
+		"https://tenant-b.auth0.com/": {
+			WithSecretProvider(defaultSecretProviderES384),
+			WithAlgorithms(jose.ES384),
+			WithIssuer("https://other.auth0.com/"),
+			WithLeeway(2 * time.Hour),
+		},
+	}, WithAudience(defaultAudience...), WithClock(clock))
+	assert.NoError(t, err)
+
+	expiry := clock.Now().Add(-time.Hour)
+	tests := []struct {
+		name           string
+		token          string
+		expectedReason ValidationReason
+	}{
+		{
+			name:           "expired",
+			token:          getTestToken(defaultAudience, "https://tenant-a.auth0.com/", expiry, jose.HS256, defaultSecret),
+			expectedReason: ReasonExpired,
+		},
+		{
+			name:  "within the leeway of the issuer",
+			token: getTestToken(defaultAudience, "https://tenant-b.auth0.com/", expiry, jose.ES384, defaultSecretES384),
+		},
+		{
+			name:           "common audience",
+			token:          getTestToken([]string{"other"}, "https://tenant-b.auth0.com/", expiry, jose.ES384, defaultSecretES384),
+			expectedReason: ReasonAudience,
+		},
+		{
+			name:           "unknown issuer",
+			token:          getTestToken(defaultAudience, "https://other.auth0.com/", expiry, jose.ES384, defaultSecretES384),
+			expectedReason: ReasonIssuer,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest("", "http://localhost", nil)
+			req.Header.Add("Authorization", "Bearer "+test.token)
+
+			_, err := validator.ValidateRequest(req)
+			if test.expectedReason == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, &ValidationError{Reason: test.expectedReason}), "got: %v", err)
+		})
+	}
+
+	_, err = NewMultiIssuerValidatorWithOptions(map[string][]Option{
+		"https://tenant-a.auth0.com/": {WithSecretProvider(defaultSecretProvider), WithAlgorithms(jose.HS256)},
+	})
+	assert.True(t, errors.Is(err, ErrInvalidAlgorithm), "got: %v", err)
+}
@@ -0,0 +1,166 @@
+package auth0
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	// ErrNoSecretProvider is returned by NewValidatorWithOptions
+	// when no SecretProvider is configured.
+	ErrNoSecretProvider = errors.New("no secret provider configured")
+	// ErrMissingClaim is wrapped by the ValidationError returned
+	// when a claim required by WithRequiredClaims is missing.
+	ErrMissingClaim = errors.New("required claim is missing")
+)
+
+// Clock tells the current time. Passing a fake clock
+// to WithClock makes validations deterministic.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Option configures the validator created by NewValidatorWithOptions.
+type Option func(*validatorOptions)
+
+type validatorOptions struct {
+	config         Configuration
+	extractor      RequestTokenExtractor
+	leeway         time.Duration
+	clock          Clock
+	requiredClaims []string
+	algorithms     []jose.SignatureAlgorithm
+	allowHMAC      bool
+}
+
+// WithSecretProvider sets the provider of the keys verifying the tokens.
+// It is required.
+func WithSecretProvider(provider SecretProvider) Option {
+	return func(o *validatorOptions) {
+		o.config.secretProvider = provider
+	}
+}
+
+// WithAudience sets the audiences the tokens must all have.
+func WithAudience(audience ...string) Option {
+	return func(o *validatorOptions) {
+		o.config.expectedClaims.Audience = audience
+	}
+}
+
+// WithIssuer sets the issuer the tokens must have.
+func WithIssuer(issuer string) Option {
+	return func(o *validatorOptions) {
+		o.config.expectedClaims.Issuer = issuer
+	}
+}
+
+// WithAlgorithms sets the algorithms the tokens may be signed with,
+// as NewConfigurationWithAlgorithms does. Without it, the algorithm
+// is not enforced and the secret provider is trusted.
+func WithAlgorithms(algorithms ...jose.SignatureAlgorithm) Option {
+	return func(o *validatorOptions) {
+		o.algorithms = append([]jose.SignatureAlgorithm{}, algorithms...)
+	}
+}
+
+// WithHMAC allows the HMAC algorithms passed to WithAlgorithms.
+func WithHMAC() Option {
+	return func(o *validatorOptions) {
+		o.allowHMAC = true
+	}
+}
+
+// WithLeeway sets the leeway used to compare time values.
+// Defaults to jwt.DefaultLeeway.
+func WithLeeway(leeway time.Duration) Option {
+	return func(o *validatorOptions) {
+		o.leeway = leeway
+	}
+}
+
+// WithClock sets the clock telling the time the
+// tokens are validated at. Defaults to the system clock.
+func WithClock(clock Clock) Option {
+	return func(o *validatorOptions) {
+		o.clock = clock
+	}
+}
+
+// WithExtractor sets the extractor of the tokens of the
+// requests. Defaults to extracting them from the header.
+func WithExtractor(extractor RequestTokenExtractor) Option {
+	return func(o *validatorOptions) {
+		o.extractor = extractor
+	}
+}
+
+// WithRequiredClaims sets the claims the tokens must have,
+// such as sub or iat, whatever their values.
+func WithRequiredClaims(claims ...string) Option {
+	return func(o *validatorOptions) {
+		o.requiredClaims = claims
+	}
+}
+
+// withConfiguration starts from the configuration
+// created by one of the NewConfiguration functions.
+func withConfiguration(config Configuration) Option {
+	return func(o *validatorOptions) {
+		o.config = config
+	}
+}
+
+// NewValidatorWithOptions creates a new validator configured with
+// options, which are applied in order.
+func NewValidatorWithOptions(options ...Option) (*JWTValidator, error) {
+	o := newValidatorOptions(options)
+	if o.config.secretProvider == nil {
+		return nil, ErrNoSecretProvider
+	}
+	if o.algorithms != nil {
+		if err := checkAlgorithms(o.algorithms, o.allowHMAC); err != nil {
+			return nil, err
+		}
+		o.config.algorithms = o.algorithms
+	}
+	return o.validator(), nil
+}
+
+func newValidatorOptions(options []Option) validatorOptions {
+	o := validatorOptions{
+		leeway: jwt.DefaultLeeway,
+		clock:  systemClock{},
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
+func (o validatorOptions) validator() *JWTValidator {
+	extractor := o.extractor
+	if extractor == nil {
+		extractor = RequestTokenExtractorFunc(FromHeader)
+	}
+	clock := o.clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &JWTValidator{
+		config:         o.config,
+		extractor:      extractor,
+		leeway:         o.leeway,
+		clock:          clock,
+		requiredClaims: o.requiredClaims,
+	}
+}
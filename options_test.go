@@ -0,0 +1,109 @@
+package auth0
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func TestNewValidatorWithOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       []Option
+		expectedError error
+	}{
+		{name: "no secret provider", options: []Option{WithIssuer(defaultIssuer)}, expectedError: ErrNoSecretProvider},
+		{name: "HMAC not allowed", options: []Option{WithSecretProvider(defaultSecretProvider), WithAlgorithms(jose.HS256)}, expectedError: ErrInvalidAlgorithm},
+		{name: "no algorithm", options: []Option{WithSecretProvider(defaultSecretProvider), WithAlgorithms()}, expectedError: ErrInvalidAlgorithm},
+		{name: "HMAC allowed", options: []Option{WithSecretProvider(defaultSecretProvider), WithAlgorithms(jose.HS256), WithHMAC()}},
+		{name: "trust provider", options: []Option{WithSecretProvider(defaultSecretProvider)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator, err := NewValidatorWithOptions(test.options...)
+			if test.expectedError != nil {
+				assert.True(t, errors.Is(err, test.expectedError), "got: %v", err)
+				assert.Nil(t, validator)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNewValidatorWithOptions(t *testing.T) {
+	clock := newFakeClock()
+	now := clock.Now()
+	claims := jwt.Claims{
+		Issuer:    defaultIssuer,
+		Audience:  defaultAudience,
+		Subject:   "user",
+		Expiry:    jwt.NewNumericDate(now.Add(time.Hour)),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+	token := getTestTokenWithClaims(jose.HS256, defaultSecret, claims)
+
+	tests := []struct {
+		name           string
+		options        []Option
+		expectedReason ValidationReason
+	}{
+		{
+			name: "valid",
+			options: []Option{
+				WithAudience(defaultAudience...),
+				WithIssuer(defaultIssuer),
+				WithAlgorithms(jose.HS256),
+				WithHMAC(),
+				WithRequiredClaims("sub", "exp"),
+			},
+		},
+		{name: "audience", options: []Option{WithAudience("other")}, expectedReason: ReasonAudience},
+		{name: "issuer", options: []Option{WithIssuer("other")}, expectedReason: ReasonIssuer},
+		{name: "algorithm", options: []Option{WithAlgorithms(jose.RS256)}, expectedReason: ReasonAlgorithm},
+		{name: "valid now", options: []Option{WithClock(clock)}},
+		{name: "expired", options: []Option{WithClock(&fakeClock{now: now.Add(2 * time.Hour)})}, expectedReason: ReasonExpired},
+		{name: "not yet valid", options: []Option{WithClock(&fakeClock{now: now.Add(-2 * time.Hour)})}, expectedReason: ReasonNotYetValid},
+		{name: "within leeway", options: []Option{WithClock(&fakeClock{now: now.Add(2 * time.Hour)}), WithLeeway(2 * time.Hour)}},
+		{name: "required claim", options: []Option{WithRequiredClaims("sub", "iat")}, expectedReason: ReasonClaim},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator, err := NewValidatorWithOptions(append([]Option{WithSecretProvider(defaultSecretProvider)}, test.options...)...)
+			assert.NoError(t, err)
+
+			req, _ := http.NewRequest("", "http://localhost", nil)
+			req.Header.Add("Authorization", "Bearer "+token)
+			_, err = validator.ValidateRequest(req)
+			if test.expectedReason == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, &ValidationError{Reason: test.expectedReason}), "got: %v", err)
+		})
+	}
+}
+
+func TestNewValidatorWithOptionsRequiredClaims(t *testing.T) {
+	validator, err := NewValidatorWithOptions(WithSecretProvider(defaultSecretProvider), WithRequiredClaims("sub"))
+	assert.NoError(t, err)
+
+	token, _ := jwt.ParseSigned(getTestTokenWithClaims(jose.HS256, defaultSecret, defaultTestClaims()))
+	err = validator.ValidateToken(token)
+	assert.True(t, errors.Is(err, ErrMissingClaim))
+	assert.EqualError(t, err, "invalid claim: required claim is missing: sub")
+}
+
+func TestNewValidatorWithOptionsExtractor(t *testing.T) {
+	validator, err := NewValidatorWithOptions(WithSecretProvider(defaultSecretProvider), WithExtractor(RequestTokenExtractorFunc(FromParams)))
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("", "http://localhost?token="+getTestTokenWithClaims(jose.HS256, defaultSecret, defaultTestClaims()), nil)
+	_, err = validator.ValidateRequest(req)
+	assert.NoError(t, err)
+}
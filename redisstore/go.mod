@@ -0,0 +1,25 @@
+module github.com/auth0-community/go-auth0/redisstore
+
+go 1.18
+
+require (
+	github.com/alicebob/miniredis/v2 v2.35.0
+	github.com/auth0-community/go-auth0 v1.1.0
+	github.com/redis/go-redis/v9 v9.7.3
+	github.com/stretchr/testify v1.4.0
+	gopkg.in/square/go-jose.v2 v2.1.7
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/crypto v0.0.0-20180802221240-56440b844dfe // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+)
+
+// The replace directive only applies when building within this repository,
+// importers use the required release, the first with the shared key cacher.
+replace github.com/auth0-community/go-auth0 => ../
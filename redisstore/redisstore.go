@@ -0,0 +1,44 @@
+// Package redisstore keeps the keys of the go-auth0 shared
+// key cacher in Redis, so that a fleet of processes
+// downloads the JSON Web Key Set only once between them.
+//
+//	store := redisstore.New(redis.NewClient(&redis.Options{Addr: "localhost:6379"}))
+//	keyCacher := auth0.NewSharedKeyCacher(store, auth0.SharedKeyCacherOptions{})
+//	client := auth0.NewJWKClientWithCache(opts, nil, keyCacher)
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	auth0 "github.com/auth0-community/go-auth0"
+	"github.com/redis/go-redis/v9"
+)
+
+// Store implements the auth0.KeyStore interface with Redis.
+type Store struct {
+	client redis.Cmdable
+}
+
+var _ auth0.KeyStore = (*Store)(nil)
+
+// New creates a Store using client, which may be a
+// single node, a cluster or a ring client.
+func New(client redis.Cmdable) *Store {
+	return &Store{client}
+}
+
+// Get implements the Get method of the auth0.KeyStore interface.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, auth0.ErrNoKeyFound
+	}
+	return value, err
+}
+
+// Set implements the Set method of the auth0.KeyStore interface.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
@@ -0,0 +1,66 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	auth0 "github.com/auth0-community/go-auth0"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func newTestStore(t *testing.T) (*miniredis.Miniredis, *Store) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, New(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+}
+
+func TestStore(t *testing.T) {
+	server, store := newTestStore(t)
+	defer server.Close()
+	ctx := context.Background()
+
+	_, err := store.Get(ctx, "key")
+	assert.Equal(t, auth0.ErrNoKeyFound, err)
+
+	assert.NoError(t, store.Set(ctx, "key", []byte("value"), time.Minute))
+	value, err := store.Get(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+	assert.Equal(t, time.Minute, server.TTL("key"))
+
+	server.FastForward(time.Minute)
+	_, err = store.Get(ctx, "key")
+	assert.Equal(t, auth0.ErrNoKeyFound, err)
+
+	server.Close()
+	_, err = store.Get(ctx, "key")
+	assert.Error(t, err)
+	assert.NotEqual(t, auth0.ErrNoKeyFound, err)
+}
+
+func TestSharedKeyCacher(t *testing.T) {
+	server, store := newTestStore(t)
+	defer server.Close()
+
+	downloadedKeys := []jose.JSONWebKey{
+		{Key: []byte("secret"), KeyID: "key1", Algorithm: string(jose.HS256)},
+		{Key: []byte("other secret"), KeyID: "key2", Algorithm: string(jose.HS256)},
+	}
+	keyCacher := auth0.NewSharedKeyCacher(store, auth0.SharedKeyCacherOptions{Prefix: "tenant:", TTL: time.Hour})
+	_, err := keyCacher.Add("key1", downloadedKeys)
+	assert.NoError(t, err)
+	assert.True(t, server.Exists("tenant:key2"))
+	assert.Equal(t, time.Hour, server.TTL("tenant:key2"))
+
+	other := auth0.NewSharedKeyCacher(store, auth0.SharedKeyCacherOptions{Prefix: "tenant:"})
+	key, err := other.Get("key2")
+	assert.NoError(t, err)
+	assert.Equal(t, "key2", key.KeyID)
+	assert.Equal(t, []byte("other secret"), key.Key)
+}
@@ -36,7 +36,7 @@ func FromMultiple(extractors ...RequestTokenExtractor) RequestTokenExtractor {
 	return RequestTokenExtractorFunc(func(r *http.Request) (*jwt.JSONWebToken, error) {
 		for _, e := range extractors {
 			token, err := e.Extract(r)
-			if err == ErrTokenNotFound {
+			if errors.Is(err, ErrTokenNotFound) {
 				continue
 			} else if err != nil {
 				return nil, err
@@ -62,7 +62,7 @@ func FromHeader(r *http.Request) (*jwt.JSONWebToken, error) {
 	if raw == "" {
 		return nil, ErrTokenNotFound
 	}
-	return jwt.ParseSigned(raw)
+	return parseSigned(raw)
 }
 
 // FromParams returns the JWT when passed as the URL query param "token".
@@ -74,7 +74,7 @@ func FromParams(r *http.Request) (*jwt.JSONWebToken, error) {
 	if raw == "" {
 		return nil, ErrTokenNotFound
 	}
-	return jwt.ParseSigned(raw)
+	return parseSigned(raw)
 }
 
 // FromCookie returns the JWT when passed in a Cookie as "access_token".
@@ -83,5 +83,14 @@ func FromCookie(r *http.Request) (*jwt.JSONWebToken, error) {
 	if err != nil {
 		return nil, ErrTokenNotFound
 	}
-	return jwt.ParseSigned(raw.Value)
+	return parseSigned(raw.Value)
+}
+
+// parseSigned parses raw, reporting a malformed token on failure.
+func parseSigned(raw string) (*jwt.JSONWebToken, error) {
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return nil, newValidationError(ReasonMalformed, err)
+	}
+	return token, nil
 }
@@ -1,6 +1,7 @@
 package auth0
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -99,7 +100,9 @@ func TestFromMultipleExtraction(t *testing.T) {
 	for _, r := range []*http.Request{headerTokenRequest, paramTokenRequest, brokenParamTokenRequest, cookieTokenRequest} {
 		token, err := extractor.Extract(r)
 		if err != nil {
-			if r == brokenParamTokenRequest && err.Error() == "square/go-jose: compact JWS format must have three parts" {
+			var validationErr *ValidationError
+			if r == brokenParamTokenRequest && errors.As(err, &validationErr) && validationErr.Reason == ReasonMalformed &&
+				validationErr.Err.Error() == "square/go-jose: compact JWS format must have three parts" {
 				// Checking that the JWT error is returned.
 				continue
 			}